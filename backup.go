@@ -0,0 +1,374 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"lexicon/lexdb"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// Archives are encrypted with AES-256-GCM, with a key derived from the passphrase with PBKDF2. The
+// file holds encMagic, the salt, the nonce and the sealed content.
+const (
+	encMagic      = "LEXICON-ENC1\n"
+	encSaltSize   = 16
+	encIterations = 600_000
+)
+
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// snapshotFormat is the timestamp in the names of the backups.
+const snapshotFormat = "20060102-150405.000000000"
+
+// backupOptions are the flags of the db backup command.
+type backupOptions struct {
+	dir     string // Directory of the backups, "backups" next to the database by default.
+	keep    int    // Number of backups kept, 0 keeps them all.
+	gzip    bool   // Compress the backup.
+	encrypt bool   // Encrypt the backup with a passphrase.
+}
+
+// backupDir returns the directory of the backups of the database.
+func backupDir(app *app, dir string) string {
+	if dir != "" {
+		return dir
+	}
+	return filepath.Join(filepath.Dir(lexdb.FilePath(app.profile.DB)), "backups")
+}
+
+// backupPrefix returns the prefix of the backups of the database: its file name without extension.
+func backupPrefix(app *app) string {
+	base := filepath.Base(lexdb.FilePath(app.profile.DB))
+	return strings.TrimSuffix(base, filepath.Ext(base)) + "-"
+}
+
+// backup writes a timestamped snapshot of the database, optionally compressed and encrypted, and
+// deletes the oldest snapshots beyond opts.keep.
+func backup(ctx context.Context, app *app, opts backupOptions) error {
+	if opts.keep < 0 {
+		return usageError{"--keep can't be negative"}
+	}
+	var passphrase string
+	if opts.encrypt {
+		var err error
+		if passphrase, err = readPassphrase(true); err != nil {
+			return err
+		}
+	}
+
+	x, err := openDB(app)
+	if err != nil {
+		return err
+	}
+	defer x.Close()
+
+	path, err := snapshot(ctx, app, x.Backup, opts.dir)
+	if err != nil {
+		return err
+	}
+	if opts.gzip || opts.encrypt {
+		archive, err := writeArchive(path, opts.gzip, passphrase)
+		os.Remove(path)
+		if err != nil {
+			return err
+		}
+		path = archive
+	}
+	fmt.Printf("Backed up %s to %s\n", lexdb.FilePath(app.profile.DB), path)
+	return rotateBackups(backupDir(app, opts.dir), backupPrefix(app), opts.keep)
+}
+
+// snapshot copies the database with backupTo to a new file in the backup directory and returns its
+// path. Names have nanoseconds so that backups taken in the same second don't collide, and still
+// sort by time.
+func snapshot(ctx context.Context, app *app, backupTo func(context.Context, string) error, dir string) (string, error) {
+	dir = backupDir(app, dir)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, backupPrefix(app)+time.Now().Format(snapshotFormat)+".sqlite")
+	if _, err := os.Stat(path); err == nil {
+		return "", fmt.Errorf("%s already exists", path)
+	}
+	if err := backupTo(ctx, path); err != nil {
+		os.Remove(path)
+		return "", fmt.Errorf("unable to back up the database: %s", err)
+	}
+	return path, os.Chmod(path, 0600)
+}
+
+// writeArchive compresses and/or encrypts the file in path to a new file with the .gz and .enc
+// extensions, and returns the path of the new file.
+func writeArchive(path string, compress bool, passphrase string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	if compress {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(content); err != nil {
+			return "", err
+		}
+		if err := zw.Close(); err != nil {
+			return "", err
+		}
+		content = buf.Bytes()
+		path += ".gz"
+	}
+	if passphrase != "" {
+		if content, err = encrypt(content, passphrase); err != nil {
+			return "", err
+		}
+		path += ".enc"
+	}
+	return path, os.WriteFile(path, content, 0600)
+}
+
+// readArchive returns the SQLite database in an archive written by writeArchive, or in a plain
+// database file. The database is written to a temporary file that the caller must remove.
+func readArchive(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	if bytes.HasPrefix(content, []byte(encMagic)) {
+		passphrase, err := readPassphrase(false)
+		if err != nil {
+			return "", err
+		}
+		if content, err = decrypt(content, passphrase); err != nil {
+			return "", err
+		}
+	}
+	if bytes.HasPrefix(content, gzipMagic) {
+		zr, err := gzip.NewReader(bytes.NewReader(content))
+		if err != nil {
+			return "", err
+		}
+		if content, err = io.ReadAll(zr); err != nil {
+			return "", fmt.Errorf("unable to decompress %s: %s", path, err)
+		}
+	}
+
+	tmp, err := os.CreateTemp("", "lexicon-restore-*.sqlite")
+	if err != nil {
+		return "", err
+	}
+	_, err = tmp.Write(content)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	return tmp.Name(), nil
+}
+
+func encryptionKey(passphrase string, salt []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(pbkdf2SHA256([]byte(passphrase), salt, encIterations))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// pbkdf2SHA256 derives a 32 bytes key from password with PBKDF2-HMAC-SHA256 (RFC 8018), which is
+// a single block of output.
+func pbkdf2SHA256(password, salt []byte, iterations int) []byte {
+	mac := hmac.New(sha256.New, password)
+	mac.Write(salt)
+	mac.Write([]byte{0, 0, 0, 1})
+	u := mac.Sum(nil)
+	key := append([]byte(nil), u...)
+	for i := 1; i < iterations; i++ {
+		mac.Reset()
+		mac.Write(u)
+		u = mac.Sum(u[:0])
+		for j := range key {
+			key[j] ^= u[j]
+		}
+	}
+	return key
+}
+
+func encrypt(content []byte, passphrase string) ([]byte, error) {
+	salt := make([]byte, encSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	aead, err := encryptionKey(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	out := append([]byte(encMagic), salt...)
+	out = append(out, nonce...)
+	return aead.Seal(out, nonce, content, []byte(encMagic)), nil
+}
+
+func decrypt(content []byte, passphrase string) ([]byte, error) {
+	content = content[len(encMagic):]
+	if len(content) < encSaltSize {
+		return nil, errors.New("the archive is truncated")
+	}
+	salt, content := content[:encSaltSize], content[encSaltSize:]
+	aead, err := encryptionKey(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	if len(content) < aead.NonceSize() {
+		return nil, errors.New("the archive is truncated")
+	}
+	nonce, content := content[:aead.NonceSize()], content[aead.NonceSize():]
+	plain, err := aead.Open(nil, nonce, content, []byte(encMagic))
+	if err != nil {
+		return nil, errors.New("wrong passphrase or corrupt archive")
+	}
+	return plain, nil
+}
+
+// readPassphrase returns the passphrase of the archives: $LEXICON_BACKUP_PASSPHRASE if set,
+// otherwise the one typed on the terminal, twice if confirm is set.
+func readPassphrase(confirm bool) (string, error) {
+	if p := os.Getenv("LEXICON_BACKUP_PASSPHRASE"); p != "" {
+		return p, nil
+	}
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", errors.New("missing passphrase, set LEXICON_BACKUP_PASSPHRASE")
+	}
+	read := func(prompt string) (string, error) {
+		fmt.Fprint(os.Stderr, prompt)
+		p, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		return string(p), err
+	}
+	p, err := read("Passphrase: ")
+	if err != nil {
+		return "", err
+	}
+	if p == "" {
+		return "", errors.New("the passphrase can't be empty")
+	}
+	if confirm {
+		again, err := read("Repeat the passphrase: ")
+		if err != nil {
+			return "", err
+		}
+		if again != p {
+			return "", errors.New("the passphrases don't match")
+		}
+	}
+	return p, nil
+}
+
+// rotateBackups deletes the oldest backups in dir of the database whose backups start with prefix,
+// keeping the newest keep. Zero keeps them all. Files whose names aren't exactly those of backups
+// of the database, e.g. the backups of another database whose name starts the same, are ignored.
+func rotateBackups(dir, prefix string, keep int) error {
+	if keep == 0 {
+		return nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	type backup struct {
+		name string
+		time time.Time
+	}
+	var backups []backup
+	for _, e := range entries {
+		if t, ok := backupTime(e.Name(), prefix); ok && !e.IsDir() {
+			backups = append(backups, backup{e.Name(), t})
+		}
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].time.Before(backups[j].time) })
+	for len(backups) > keep {
+		path := filepath.Join(dir, backups[0].name)
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+		fmt.Printf("Deleted %s\n", path)
+		backups = backups[1:]
+	}
+	return nil
+}
+
+// backupTime returns the time of the backup called name: prefix, a timestamp and the .sqlite
+// extension, followed by .gz and .enc if it's compressed or encrypted. ok is false if name isn't
+// such a backup.
+func backupTime(name, prefix string) (t time.Time, ok bool) {
+	if !strings.HasPrefix(name, prefix) {
+		return t, false
+	}
+	name = strings.TrimPrefix(name, prefix)
+	name = strings.TrimSuffix(name, ".enc")
+	name = strings.TrimSuffix(name, ".gz")
+	if !strings.HasSuffix(name, ".sqlite") {
+		return t, false
+	}
+	// Parsing accepts the nanoseconds of snapshotFormat after the seconds without asking for them,
+	// which the backups written before they were added don't have.
+	t, err := time.ParseInLocation("20060102-150405", strings.TrimSuffix(name, ".sqlite"), time.Local)
+	return t, err == nil
+}
+
+// restore replaces the database with a backup. The current database is backed up first.
+func restore(ctx context.Context, app *app, path string, yes bool) error {
+	db, err := readArchive(path)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(db)
+	if err := lexdb.Verify(ctx, db); err != nil {
+		return fmt.Errorf("not restored: %s", strings.Replace(err.Error(), db, path, 1))
+	}
+
+	x, err := openDB(app)
+	if err != nil {
+		return err
+	}
+	defer x.Close()
+
+	if !yes {
+		ok, err := confirm(ctx, app, fmt.Sprintf("Replace %s with %s?", lexdb.FilePath(app.profile.DB), path))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errors.New("not restored, run with --yes to restore without confirmation")
+		}
+	}
+
+	previous, err := snapshot(ctx, app, x.Backup, "")
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Backed up the current database to %s\n", previous)
+	if err := x.Restore(ctx, db); err != nil {
+		return err
+	}
+	fmt.Printf("Restored %s from %s\n", lexdb.FilePath(app.profile.DB), path)
+	return nil
+}
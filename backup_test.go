@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"lexicon/config"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Test vectors of PBKDF2-HMAC-SHA256 from RFC 7914 section 11. pbkdf2SHA256 derives a single
+// block, the first 32 bytes of the 64 of the RFC.
+func TestPBKDF2SHA256(t *testing.T) {
+	tests := []struct {
+		password, salt string
+		iterations     int
+		want           string
+	}{
+		{"passwd", "salt", 1, "55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc"},
+		{"Password", "NaCl", 80000, "4ddcd8f60b98be21830cee5ef22701f9641a4418d04c0414aeff08876b34ab56"},
+	}
+	for _, tt := range tests {
+		got := hex.EncodeToString(pbkdf2SHA256([]byte(tt.password), []byte(tt.salt), tt.iterations))
+		if got != tt.want {
+			t.Errorf("pbkdf2SHA256(%q, %q, %d) = %s, want %s", tt.password, tt.salt, tt.iterations, got, tt.want)
+		}
+	}
+}
+
+func TestEncrypt(t *testing.T) {
+	content := []byte("SQLite format 3\x00 and the rest of the database")
+	sealed, err := encrypt(content, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(sealed, []byte(encMagic)) {
+		t.Fatalf("encrypted content doesn't start with %q", encMagic)
+	}
+	if bytes.Contains(sealed, content) {
+		t.Fatal("encrypted content holds the plain text")
+	}
+
+	plain, err := decrypt(sealed, "secret")
+	if err != nil {
+		t.Fatalf("decrypt returned %v", err)
+	}
+	if !bytes.Equal(plain, content) {
+		t.Errorf("decrypt returned %q, want %q", plain, content)
+	}
+
+	if _, err := decrypt(sealed, "wrong"); err == nil {
+		t.Error("decrypt with the wrong passphrase succeeded")
+	}
+}
+
+func TestDecryptTruncated(t *testing.T) {
+	sealed, err := encrypt([]byte("content"), "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce := len(encMagic) + encSaltSize
+	for _, n := range []int{len(encMagic), len(encMagic) + encSaltSize/2, nonce, nonce + 6, len(sealed) - 1} {
+		if _, err := decrypt(sealed[:n], "secret"); err == nil {
+			t.Errorf("decrypt of the first %d of %d bytes succeeded", n, len(sealed))
+		}
+	}
+}
+
+func TestArchive(t *testing.T) {
+	if old, ok := os.LookupEnv("LEXICON_BACKUP_PASSPHRASE"); ok {
+		defer os.Setenv("LEXICON_BACKUP_PASSPHRASE", old)
+	} else {
+		defer os.Unsetenv("LEXICON_BACKUP_PASSPHRASE")
+	}
+	os.Setenv("LEXICON_BACKUP_PASSPHRASE", "secret")
+	content := []byte("SQLite format 3\x00")
+	for _, tt := range []struct {
+		compress   bool
+		passphrase string
+		ext        string
+	}{
+		{false, "", ".sqlite"},
+		{true, "", ".sqlite.gz"},
+		{false, "secret", ".sqlite.enc"},
+		{true, "secret", ".sqlite.gz.enc"},
+	} {
+		path := filepath.Join(t.TempDir(), "lexicon.sqlite")
+		if err := os.WriteFile(path, content, 0600); err != nil {
+			t.Fatal(err)
+		}
+		archive, err := writeArchive(path, tt.compress, tt.passphrase)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasSuffix(archive, tt.ext) {
+			t.Errorf("writeArchive(compress %v, encrypt %v) wrote %s, want extension %s", tt.compress, tt.passphrase != "", archive, tt.ext)
+		}
+
+		db, err := readArchive(archive)
+		if err != nil {
+			t.Fatalf("readArchive(%s) returned %v", archive, err)
+		}
+		got, err := os.ReadFile(db)
+		os.Remove(db)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, content) {
+			t.Errorf("readArchive(%s) returned %q, want %q", archive, got, content)
+		}
+	}
+}
+
+func TestSnapshotNames(t *testing.T) {
+	dir := t.TempDir()
+	a := &app{profile: config.Profile{DB: filepath.Join(dir, "lexicon.sqlite")}}
+	backupTo := func(ctx context.Context, path string) error {
+		return os.WriteFile(path, nil, 0600)
+	}
+
+	var paths []string
+	for i := 0; i < 3; i++ {
+		path, err := snapshot(context.Background(), a, backupTo, "")
+		if err != nil {
+			t.Fatalf("snapshot %d returned %v", i, err)
+		}
+		paths = append(paths, path)
+	}
+	for i := 1; i < len(paths); i++ {
+		if paths[i] <= paths[i-1] {
+			t.Errorf("snapshot names %s and %s don't sort by time", paths[i-1], paths[i])
+		}
+	}
+}
+
+func TestRotateBackups(t *testing.T) {
+	dir := t.TempDir()
+	names := []string{
+		// lexicon.sqlite
+		"lexicon-20260101-120000.sqlite",
+		"lexicon-20260102-120000.000000001.sqlite.gz",
+		"lexicon-20260102-120000.000000002.sqlite.enc",
+		"lexicon-20260103-120000.000000000.sqlite.gz.enc",
+		// lexicon-work.sqlite, whose prefix starts with that of lexicon.sqlite.
+		"lexicon-work-20250101-120000.000000000.sqlite",
+		"lexicon-work-20270101-120000.000000000.sqlite",
+		// Other files.
+		"lexicon-20200101-120000.000000000.sqlite-journal",
+		"lexicon-notes.sqlite",
+		"lexicon-20200101-120000.000000000.txt",
+	}
+	for _, name := range names {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	prefix := func(db string) string {
+		return backupPrefix(&app{profile: config.Profile{DB: filepath.Join(dir, db)}})
+	}
+	if err := rotateBackups(dir, prefix("lexicon.sqlite"), 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := rotateBackups(dir, prefix("lexicon-work.sqlite?_busy_timeout=100"), 1); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, e := range entries {
+		got = append(got, e.Name())
+	}
+	want := []string{
+		"lexicon-20200101-120000.000000000.sqlite-journal",
+		"lexicon-20200101-120000.000000000.txt",
+		"lexicon-20260102-120000.000000002.sqlite.enc",
+		"lexicon-20260103-120000.000000000.sqlite.gz.enc",
+		"lexicon-notes.sqlite",
+		"lexicon-work-20270101-120000.000000000.sqlite",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("rotateBackups kept\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
@@ -0,0 +1,443 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"lexicon/config"
+	"lexicon/dictapi"
+	"lexicon/importer"
+	"lexicon/types"
+	"lexicon/util"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mattn/go-isatty"
+)
+
+// batchItem is a record of a define-batch input file.
+type batchItem struct {
+	index  int
+	record importer.Record
+}
+
+// key identifies the item in the checkpoint file.
+func (b batchItem) key() string {
+	return fmt.Sprintf("%d\t%s", b.record.Line, b.record.Word)
+}
+
+// batchResult is the outcome of defining a batchItem.
+type batchResult struct {
+	item batchItem
+	err  error
+}
+
+// batchFailure describes a word that could not be defined. Failures are written to the report file
+// as a JSON array so they can be processed by other tools.
+type batchFailure struct {
+	Line        int      `json:"line"`
+	Word        string   `json:"word"`
+	Reason      string   `json:"reason"`
+	Suggestions []string `json:"suggestions,omitempty"`
+}
+
+// batchOptions are the flags of the define-batch command.
+type batchOptions struct {
+	concurrency int     // Number of words defined in parallel.
+	rate        float64 // Maximum number of dictionaryapi.com and merriam-webster.com requests per second.
+	checkpoint  string  // Checkpoint file, <file>.checkpoint by default.
+	report      string  // Failure report file, <file>.failures.json by default.
+	format      string  // Input format, detected from the file name by default.
+}
+
+// defineBatch reads words from a file and defines all words in it. If the words contain a timestamp
+// the createdAt and updatedAt timestamps are set to such timestamp. This command is useful for
+// importing words from other sources while still keeping the original dates. The file can be in any
+// of the formats supported by the importer package.
+//
+// Words are defined by a pool of workers whose requests to dictionaryapi.com and merriam-webster.com
+// are bounded by a rate limiter. Records of the same word are handled by one worker, in order, so
+// that they don't race to save it. Every successful line is recorded in a checkpoint file so that
+// an interrupted batch resumes where it stopped, and failures are written to a JSON report.
+func defineBatch(ctx context.Context, app *app, fileName string, opts batchOptions) error {
+	if opts.concurrency < 1 || opts.rate <= 0 {
+		return usageError{"concurrency and rate must be positive"}
+	}
+	checkpoint := opts.checkpoint
+	if checkpoint == "" {
+		checkpoint = fileName + ".checkpoint"
+	}
+	report := opts.report
+	if report == "" {
+		report = fileName + ".failures.json"
+	}
+
+	records, err := importer.Read(opts.format, fileName)
+	if err != nil {
+		return err
+	}
+	var items []batchItem
+	for i, rec := range records {
+		items = append(items, batchItem{index: i, record: rec})
+	}
+
+	done, err := readCheckpoint(checkpoint, items)
+	if err != nil {
+		return err
+	}
+	if len(done) > 0 {
+		log.Printf("Resuming from %s, %d of %d words already defined", checkpoint, len(done), len(items))
+	}
+
+	cp, err := os.OpenFile(checkpoint, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer cp.Close()
+
+	var pending []batchItem
+	for _, item := range items {
+		if !done[item.index] {
+			pending = append(pending, item)
+		}
+	}
+
+	bar := newProgress(os.Stdout, len(pending))
+	logOutput := log.Writer()
+	log.SetOutput(bar)
+	defer log.SetOutput(logOutput)
+
+	limiter := newRateLimiter(opts.rate)
+	defer limiter.stop()
+
+	queue := make(chan []batchItem)
+	results := make(chan batchResult)
+	var wg sync.WaitGroup
+	for i := 0; i < opts.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for group := range queue {
+				for _, item := range group {
+					results <- batchResult{item: item, err: defineBatchItem(ctx, app, limiter, item)}
+				}
+			}
+		}()
+	}
+	go func() {
+		defer close(queue)
+		for _, group := range groupByWord(pending) {
+			select {
+			case <-ctx.Done():
+				return
+			case queue <- group:
+			}
+		}
+	}()
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var failures []batchFailure
+	succeeded := 0
+	for res := range results {
+		switch {
+		case res.err == nil:
+			succeeded++
+			if _, err := fmt.Fprintf(cp, "%s\n", res.item.key()); err != nil {
+				log.Printf("Unable to write checkpoint: %s", err)
+			}
+			bar.increment(false)
+		case ctx.Err() != nil:
+			// Interrupted, the word is still pending.
+		default:
+			failures = append(failures, newBatchFailure(res.item, res.err))
+			bar.increment(true)
+		}
+	}
+	bar.finish()
+	log.SetOutput(logOutput)
+	sort.Slice(failures, func(i, j int) bool { return failures[i].Line < failures[j].Line })
+
+	remaining := len(pending) - succeeded - len(failures)
+	log.Println()
+	log.Printf("Successful definitions: %d", succeeded)
+	log.Printf("Failed definitions: %d", len(failures))
+	for _, f := range failures {
+		log.Printf("• %s (line %d): %s", f.Word, f.Line, strings.TrimSpace(f.Reason))
+	}
+
+	if len(failures) > 0 {
+		if err := writeFailureReport(report, failures); err != nil {
+			log.Printf("Unable to write failure report: %s", err)
+		} else {
+			log.Printf("Failure report written to %s", report)
+		}
+	}
+
+	if remaining > 0 {
+		log.Printf("Interrupted, pending definitions: %d. Run the same command again to resume.", remaining)
+		return ctx.Err()
+	}
+
+	if len(failures) > 0 {
+		// Keep the checkpoint so that running the batch again only retries the failed words.
+		return nil
+	}
+
+	// The batch is complete, the checkpoint is no longer needed.
+	cp.Close()
+	if err := os.Remove(checkpoint); err != nil {
+		log.Printf("Unable to remove checkpoint: %s", err)
+	}
+	return nil
+}
+
+// defineBatchItem defines and saves the word in item.
+func defineBatchItem(ctx context.Context, app *app, limiter *rateLimiter, item batchItem) error {
+	dictionary := app.dictionary
+	rec := item.record
+	if rec.Err != nil {
+		return rec.Err
+	}
+
+	lexeme, err := dictionary.Find(ctx, rec.Word)
+	if err != nil && !errors.Is(err, types.NotFound) {
+		return err
+	}
+
+	if errors.Is(err, types.NotFound) {
+		if err := limiter.wait(ctx); err != nil {
+			return err
+		}
+		lexeme, err = fetchDefinition(ctx, rec.Word)
+		if err != nil {
+			return err
+		}
+		if app.profile.HasProvider(config.MerriamWebster) {
+			if err := limiter.wait(ctx); err != nil {
+				return err
+			}
+			saveOnMerriamWebster(ctx, app, rec.Word)
+		}
+
+		lexeme.CreatedAt = rec.Timestamp
+		lexeme.UpdatedAt = rec.Timestamp
+		mergeRecord(lexeme, rec)
+		return dictionary.Save(ctx, lexeme)
+	}
+
+	changed := mergeRecord(lexeme, rec)
+	if rec.Timestamp != nil {
+		// Rewrite the timestamps of the existing entry.
+		lexeme.CreatedAt = rec.Timestamp
+		lexeme.UpdatedAt = rec.Timestamp
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+	return dictionary.Upsert(ctx, lexeme)
+}
+
+// groupByWord groups the items of the same word, in the order of their first occurrence.
+func groupByWord(items []batchItem) [][]batchItem {
+	var groups [][]batchItem
+	index := make(map[string]int)
+	for _, item := range items {
+		if item.record.Err != nil {
+			groups = append(groups, []batchItem{item})
+			continue
+		}
+		i, ok := index[item.record.Word]
+		if !ok {
+			i = len(groups)
+			index[item.record.Word] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], item)
+	}
+	return groups
+}
+
+// mergeRecord adds the tags and notes of an imported record to lexeme. The source of the record is
+// kept in the notes unless they already mention it. Returns true if the lexeme changed.
+func mergeRecord(lexeme *types.Lexeme, rec importer.Record) bool {
+	changed := false
+	for _, tag := range rec.Tags {
+		if !util.Contains(lexeme.Tags, tag) {
+			lexeme.Tags = append(lexeme.Tags, tag)
+			changed = true
+		}
+	}
+
+	notes := rec.Notes
+	if rec.Source != "" && !strings.Contains(notes, rec.Source) {
+		if notes != "" {
+			notes += "\n"
+		}
+		notes += "Source: " + rec.Source
+	}
+	if notes != "" && !strings.Contains(lexeme.Notes, notes) {
+		if lexeme.Notes != "" {
+			lexeme.Notes += "\n"
+		}
+		lexeme.Notes += notes
+		changed = true
+	}
+	return changed
+}
+
+func newBatchFailure(item batchItem, err error) batchFailure {
+	failure := batchFailure{
+		Line:   item.record.Line,
+		Word:   item.record.Word,
+		Reason: err.Error(),
+	}
+	var serr *dictapi.SuggestionsError
+	if errors.As(err, &serr) {
+		failure.Reason = "not in the dictionary"
+		failure.Suggestions = serr.Suggestions
+	}
+	return failure
+}
+
+// readCheckpoint returns the indexes of the items that were already defined. Entries that don't
+// match the current content of the file are ignored.
+func readCheckpoint(name string, items []batchItem) (map[int]bool, error) {
+	done := make(map[int]bool)
+	f, err := os.Open(name)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return done, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	keys := make(map[string]int)
+	for _, item := range items {
+		keys[item.key()] = item.index
+	}
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if index, ok := keys[scanner.Text()]; ok {
+			done[index] = true
+		}
+	}
+	return done, scanner.Err()
+}
+
+func writeFailureReport(name string, failures []batchFailure) error {
+	buf, err := json.MarshalIndent(failures, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(name, buf, 0644)
+}
+
+// rateLimiter allows at most one event per interval.
+type rateLimiter struct {
+	ticker *time.Ticker
+}
+
+func newRateLimiter(perSecond float64) *rateLimiter {
+	return &rateLimiter{ticker: time.NewTicker(time.Duration(float64(time.Second) / perSecond))}
+}
+
+// wait blocks until the next event is allowed or ctx is cancelled.
+func (r *rateLimiter) wait(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-r.ticker.C:
+		return nil
+	}
+}
+
+func (r *rateLimiter) stop() {
+	r.ticker.Stop()
+}
+
+// progress renders a progress bar on the last line of a terminal. It also implements io.Writer so
+// that log messages are printed above the bar instead of being mixed with it.
+type progress struct {
+	mu       sync.Mutex
+	out      io.Writer
+	terminal bool
+	total    int
+	done     int
+	failed   int
+	start    time.Time
+}
+
+func newProgress(out *os.File, total int) *progress {
+	return &progress{
+		out:      out,
+		terminal: isatty.IsTerminal(out.Fd()),
+		total:    total,
+		start:    time.Now(),
+	}
+}
+
+func (p *progress) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.clear()
+	n, err := p.out.Write(b)
+	p.draw()
+	return n, err
+}
+
+func (p *progress) increment(failed bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.done++
+	if failed {
+		p.failed++
+	}
+	p.clear()
+	p.draw()
+}
+
+// finish removes the progress bar.
+func (p *progress) finish() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.clear()
+}
+
+func (p *progress) clear() {
+	if p.terminal {
+		_, _ = fmt.Fprint(p.out, "\r\033[K")
+	}
+}
+
+func (p *progress) draw() {
+	if !p.terminal || p.total == 0 {
+		return
+	}
+
+	const width = 30
+	filled := width * p.done / p.total
+	eta := "--"
+	if p.done > 0 {
+		elapsed := time.Since(p.start)
+		remaining := elapsed / time.Duration(p.done) * time.Duration(p.total-p.done)
+		eta = remaining.Round(time.Second).String()
+	}
+	_, _ = fmt.Fprintf(p.out, "[%s%s] %d/%d (%d failed) ETA %s",
+		strings.Repeat("#", filled), strings.Repeat(".", width-filled),
+		p.done, p.total, p.failed, eta)
+}
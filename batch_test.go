@@ -0,0 +1,32 @@
+package main
+
+import (
+	"errors"
+	"lexicon/importer"
+	"reflect"
+	"testing"
+)
+
+func TestGroupByWord(t *testing.T) {
+	var items []batchItem
+	for i, word := range []string{"apple", "pear", "apple", "", "", "pear", "fig"} {
+		rec := importer.Record{Line: i + 1, Word: word}
+		if word == "" {
+			rec.Err = errors.New("missing word")
+		}
+		items = append(items, batchItem{index: i, record: rec})
+	}
+
+	var got [][]int
+	for _, group := range groupByWord(items) {
+		var lines []int
+		for _, item := range group {
+			lines = append(lines, item.record.Line)
+		}
+		got = append(got, lines)
+	}
+	want := [][]int{{1, 3}, {2, 6}, {4}, {5}, {7}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("groupByWord returned lines %v, want %v", got, want)
+	}
+}
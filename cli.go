@@ -0,0 +1,469 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"lexicon/config"
+	"lexicon/dictapi"
+	"lexicon/lexapi"
+	"lexicon/lexdb"
+	"lexicon/lexdir"
+	"lexicon/render"
+	"lexicon/types"
+	"lexicon/util"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// Exit codes.
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
+
+// Backends.
+const (
+	backendSQLite = "sqlite"
+	backendAPI    = "api"
+	backendDir    = "dir"
+)
+
+// command is a lexicon subcommand.
+type command struct {
+	name  string
+	args  string // Synopsis of the positional arguments, e.g. "<word>".
+	short string // One line description.
+	// minArgs and maxArgs bound the number of positional arguments. A negative maxArgs means there's
+	// no upper bound.
+	minArgs, maxArgs int
+	// noDictionary is set for commands that don't need the dictionary, so they work even when the
+	// backend isn't configured.
+	noDictionary bool
+	flags        *flag.FlagSet
+	run          func(ctx context.Context, app *app, args []string) error
+	// subcommands are selected by the first argument, e.g. "db doctor". Their names include the
+	// name of the parent.
+	subcommands []*command
+}
+
+func newCommand(name, args, short string, minArgs, maxArgs int) *command {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	return &command{name: name, args: args, short: short, minArgs: minArgs, maxArgs: maxArgs, flags: fs}
+}
+
+// newGroup returns a command that only runs its subcommands.
+func newGroup(name, short string, subcommands ...*command) *command {
+	cmd := newCommand(name, "<command>", short, 0, -1)
+	cmd.noDictionary = true
+	cmd.subcommands = subcommands
+	cmd.run = func(ctx context.Context, app *app, args []string) error {
+		if len(args) == 0 {
+			return usageError{"missing command"}
+		}
+		return usageError{fmt.Sprintf("unknown command %q", args[0])}
+	}
+	return cmd
+}
+
+// resolve returns the subcommand selected by the first arguments, or c if there's none, and the
+// remaining arguments.
+func (c *command) resolve(args []string) (*command, []string) {
+	for len(args) > 0 {
+		var next *command
+		for _, sub := range c.subcommands {
+			if sub.name == c.name+" "+args[0] {
+				next = sub
+			}
+		}
+		if next == nil {
+			break
+		}
+		c, args = next, args[1:]
+	}
+	return c, args
+}
+
+// usageError is returned when a command is invoked with the wrong arguments.
+type usageError struct {
+	message string
+}
+
+func (e usageError) Error() string {
+	return e.message
+}
+
+// app holds the state shared by all commands.
+type app struct {
+	profileName string
+	configPath  string
+	cfg         *config.Config
+	// profile holds the effective settings: the selected profile of the configuration file overridden
+	// by the environment and the global flags.
+	profile config.Profile
+	// location is the display time zone, see config.Profile.Location.
+	location *time.Location
+	// full and short select the print mode, lines overrides the number of lines of short output.
+	full, short bool
+	lines       *int
+	global      *flag.FlagSet
+	// interactive is set during interactive sessions. Input is read with readLine, from a line
+	// editor when the session runs on a terminal.
+	interactive bool
+	input       lineInput
+	// last is the last word shown in the interactive session, pending holds its dictionary entries
+	// that didn't fit the screen.
+	last    *types.Lexeme
+	pending *render.Entry
+	// words caches the saved words and suggestions holds the spelling suggestions of the last word
+	// that wasn't in the dictionary, both for completion.
+	words       []string
+	suggestions []string
+	commands    []*command
+	dictionary  types.Dictionary
+}
+
+// globalFlags returns the flags accepted before the command name. The settings they override are
+// only applied when the flags are set, see configure.
+func (a *app) globalFlags() *flag.FlagSet {
+	fs := flag.NewFlagSet("lexicon", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	fs.StringVar(&a.profileName, "profile", "", "configuration profile (default: default_profile of the configuration file)")
+	fs.String("backend", "", "dictionary backend: sqlite, api or dir (env DATA_SOURCE_TYPE)")
+	fs.String("db", "", "SQLite database file or directory of the dir backend (env DATA_SOURCE_NAME)")
+	fs.String("format", "", "output format: "+strings.Join(outputFormats, ", ")+" (env LEXICON_FORMAT)")
+	fs.BoolVar(&a.full, "full", false, "print every sense, usage note and quote, through $PAGER if needed (default: mode of the profile)")
+	fs.BoolVar(&a.short, "short", false, "print the short definitions only (default: mode of the profile)")
+	fs.Func("lines", "cut short output after `N` lines, 0 for no limit (default: fit the terminal)", func(s string) error {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			return errors.New("expected a number of lines")
+		}
+		a.lines = &n
+		return nil
+	})
+	return fs
+}
+
+// configure loads the configuration file and resolves the settings of the selected profile. The
+// global flags that were set override the settings of the profile and the environment.
+func (a *app) configure() error {
+	a.location = time.Local
+	path, err := config.Path()
+	if err != nil {
+		return err
+	}
+	a.configPath = path
+	a.cfg, err = config.Load(path)
+	if err != nil {
+		return err
+	}
+	a.profileName = a.cfg.ProfileName(a.profileName)
+	profile, err := a.cfg.Resolve(a.profileName)
+	if err != nil {
+		return err
+	}
+
+	a.global.Visit(func(f *flag.Flag) {
+		if util.Contains(config.Keys(), f.Name) && err == nil {
+			err = profile.Set(f.Name, f.Value.String())
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if err := profile.Validate(); err != nil {
+		return err
+	}
+	if a.full && a.short {
+		return errors.New("--full and --short are mutually exclusive")
+	}
+	if !isOutputFormat(profile.Format) {
+		return fmt.Errorf("unknown output format %q", profile.Format)
+	}
+	location, err := profile.Location()
+	if err != nil {
+		return fmt.Errorf("invalid timezone %q: %s", profile.Timezone, err)
+	}
+	a.profile = profile
+	a.location = location
+
+	dictapi.APIKey = profile.DictionaryAPIKey
+	dictapi.Endpoint = profile.DictionaryAPIURL
+	dictapi.Cookie = profile.MerriamWebsterCookie
+	// In auto mode the color package already disables colors when the standard output isn't a
+	// terminal. Only the text format is colored.
+	switch {
+	case profile.Format != formatText || profile.Color == "never":
+		color.NoColor = true
+	case profile.Color == "always":
+		color.NoColor = false
+	}
+	if profile.Format != formatText {
+		// Keep the standard output parseable, messages go to the standard error.
+		log.SetOutput(os.Stderr)
+	}
+	return nil
+}
+
+// mode returns the print mode selected with --full and --short, or the mode of the profile.
+func (a *app) mode() PrintMode {
+	switch {
+	case a.full:
+		return FullDef
+	case a.short:
+		return ShortDef
+	case a.profile.Mode == config.ModeFull:
+		return FullDef
+	}
+	return ShortDef
+}
+
+// setMode applies the --full and --short flags of a command, which override the global ones.
+func (a *app) setMode(full, short bool) error {
+	if full && short {
+		return usageError{"--full and --short are mutually exclusive"}
+	}
+	if full || short {
+		a.full, a.short = full, short
+	}
+	return nil
+}
+
+// open sets up the dictionary of the selected backend.
+func (a *app) open() error {
+	d, err := openDictionary(a.profile)
+	if err != nil {
+		return err
+	}
+	a.dictionary = d
+	return nil
+}
+
+// openDictionary returns the dictionary of the backend of a profile.
+func openDictionary(profile config.Profile) (types.Dictionary, error) {
+	switch profile.Backend {
+	case backendAPI:
+		ac, err := lexapi.NewDictionary(profile.APIKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to set up API client: %s", err)
+		}
+		return ac, nil
+	case backendSQLite:
+		d, err := lexdb.NewDictionary(profile.DB)
+		if err != nil {
+			return nil, fmt.Errorf("failed to set up database: %s", err)
+		}
+		return d, nil
+	case backendDir:
+		d, err := lexdir.NewDictionary(profile.DB)
+		if err != nil {
+			return nil, fmt.Errorf("failed to set up directory: %s", err)
+		}
+		return d, nil
+	}
+	return nil, usageError{fmt.Sprintf("unknown backend %q", profile.Backend)}
+}
+
+func (a *app) close() {
+	if a.dictionary != nil {
+		_ = a.dictionary.Close()
+	}
+}
+
+func (a *app) find(name string) *command {
+	for _, cmd := range a.commands {
+		if cmd.name == name {
+			return cmd
+		}
+	}
+	return nil
+}
+
+// run parses the command line and runs the selected command. It returns the exit code.
+func (a *app) run(ctx context.Context, args []string) int {
+	a.global = a.globalFlags()
+	global := a.global
+	if err := global.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			a.usage(os.Stdout)
+			return exitOK
+		}
+		fmt.Fprintf(os.Stderr, "lexicon: %s\n\n", err)
+		a.usage(os.Stderr)
+		return exitUsage
+	}
+	// The config command must work even when the configuration is invalid, it's how it gets fixed.
+	if err := a.configure(); err != nil && global.Arg(0) != "config" {
+		fmt.Fprintf(os.Stderr, "lexicon: %s\n", err)
+		return exitUsage
+	}
+	// Backends count days in the display time zone too.
+	ctx = types.WithLocation(ctx, a.location)
+
+	if global.NArg() == 0 {
+		// Launch the lexicon in interactive mode
+		if err := a.open(); err != nil {
+			fmt.Fprintf(os.Stderr, "lexicon: %s\n", err)
+			return exitError
+		}
+		defer a.close()
+		// Ctrl-C only interrupts the current command of the session, see interactive.
+		ctx, stop := notifyContext(ctx, syscall.SIGTERM)
+		defer stop()
+		interactive(ctx, a)
+		return exitOK
+	}
+
+	name := global.Arg(0)
+	cmd := a.find(name)
+	if cmd == nil {
+		fmt.Fprintf(os.Stderr, "lexicon: unknown command %q\n\n", name)
+		a.usage(os.Stderr)
+		return exitUsage
+	}
+
+	cmd, rest := cmd.resolve(global.Args()[1:])
+	name = cmd.name
+	positional, err := cmd.parse(rest)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			cmd.usage(os.Stdout)
+			return exitOK
+		}
+		fmt.Fprintf(os.Stderr, "lexicon %s: %s\n\n", name, err)
+		cmd.usage(os.Stderr)
+		return exitUsage
+	}
+
+	if !cmd.noDictionary {
+		if err := a.open(); err != nil {
+			fmt.Fprintf(os.Stderr, "lexicon: %s\n", err)
+			return exitError
+		}
+		defer a.close()
+	}
+
+	// Cancel in-flight work on Ctrl-C or SIGTERM.
+	ctx, stop := notifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := cmd.run(ctx, a, positional); err != nil {
+		var uerr usageError
+		if errors.As(err, &uerr) {
+			fmt.Fprintf(os.Stderr, "lexicon %s: %s\n\n", name, err)
+			cmd.usage(os.Stderr)
+			return exitUsage
+		}
+		fmt.Fprintf(os.Stderr, "%s failed with error: %s\n", name, err)
+		return exitError
+	}
+	return exitOK
+}
+
+// parse parses the flags of the command and returns its positional arguments. The flags are reset
+// to their defaults first because the interactive session may run a command many times.
+func (c *command) parse(args []string) ([]string, error) {
+	c.flags.VisitAll(func(f *flag.Flag) { _ = f.Value.Set(f.DefValue) })
+	positional, err := parseInterspersed(c.flags, args)
+	if err != nil {
+		return nil, err
+	}
+	if len(positional) < c.minArgs || (c.maxArgs >= 0 && len(positional) > c.maxArgs) {
+		return nil, usageError{"wrong number of arguments"}
+	}
+	return positional, nil
+}
+
+// parseInterspersed parses flags that may appear before, after or between positional arguments
+// and returns the positional arguments. Arguments after "--" are never parsed as flags.
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var rest []string
+	for i, arg := range args {
+		if arg == "--" {
+			args, rest = args[:i], args[i+1:]
+			break
+		}
+	}
+
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			break
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+	return append(positional, rest...), nil
+}
+
+func (a *app) usage(w io.Writer) {
+	fmt.Fprintf(w, "Usage: lexicon [global flags] <command> [flags] [args]\n\n")
+	fmt.Fprintf(w, "A command line lexicon. Without a command, lexicon starts an interactive session.\n\n")
+	fmt.Fprintf(w, "Commands:\n")
+	for _, cmd := range a.commands {
+		fmt.Fprintf(w, "  %-14s %s\n", cmd.name, cmd.short)
+	}
+	fmt.Fprintf(w, "\nGlobal flags:\n")
+	a.global.SetOutput(w)
+	a.global.PrintDefaults()
+	a.global.SetOutput(io.Discard)
+	fmt.Fprintf(w, "\nRun \"lexicon help <command>\" for more information about a command.\n")
+}
+
+func (c *command) usage(w io.Writer) {
+	synopsis := "lexicon " + c.name
+	if c.hasFlags() {
+		synopsis += " [flags]"
+	}
+	if c.args != "" {
+		synopsis += " " + c.args
+	}
+	fmt.Fprintf(w, "Usage: %s\n\n%s\n", synopsis, c.short)
+	if len(c.subcommands) > 0 {
+		fmt.Fprintf(w, "\nCommands:\n")
+		for _, sub := range c.subcommands {
+			fmt.Fprintf(w, "  %-14s %s\n", sub.shortName(), sub.short)
+		}
+	}
+	if c.hasFlags() {
+		fmt.Fprintf(w, "\nFlags:\n")
+		c.flags.SetOutput(w)
+		c.flags.PrintDefaults()
+		c.flags.SetOutput(io.Discard)
+	}
+}
+
+// shortName returns the name of a subcommand without the name of its parent.
+func (c *command) shortName() string {
+	return c.name[strings.LastIndex(c.name, " ")+1:]
+}
+
+func (c *command) hasFlags() bool {
+	n := 0
+	c.flags.VisitAll(func(*flag.Flag) { n++ })
+	return n > 0
+}
+
+// flagNames returns the names of the flags in fs sorted alphabetically.
+func flagNames(fs *flag.FlagSet) []string {
+	var names []string
+	fs.VisitAll(func(f *flag.Flag) { names = append(names, f.Name) })
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"lexicon/exporter"
+	"lexicon/importer"
+	"lexicon/lexdb"
+	"os"
+	"strings"
+)
+
+// newCommands returns the commands of the lexicon in the order they're listed in the usage text.
+func newCommands() []*command {
+	return []*command{
+		defineCommand(),
+		defineBatchCommand(),
+		refreshCommand(),
+		wodCommand(),
+		statsCommand(),
+		listCommand(),
+		searchCommand(),
+		tagCommand(),
+		noteCommand(),
+		exportCommand(),
+		removeCommand(),
+		configCommand(),
+		transferCommand(),
+		dbCommand(),
+		completionCommand(),
+		helpCommand(),
+	}
+}
+
+func defineCommand() *command {
+	cmd := newCommand("define", "<word>", "Define a word and save it in the lexicon.", 1, 1)
+	full, short := modeFlags(cmd)
+	cmd.run = func(ctx context.Context, app *app, args []string) error {
+		if err := app.setMode(*full, *short); err != nil {
+			return err
+		}
+		return define(ctx, app, args[0])
+	}
+	return cmd
+}
+
+// modeFlags binds the --full and --short flags of commands that print definitions.
+func modeFlags(cmd *command) (full, short *bool) {
+	full = cmd.flags.Bool("full", false, "print every sense, usage note and quote")
+	short = cmd.flags.Bool("short", false, "print the short definitions only")
+	return full, short
+}
+
+func defineBatchCommand() *command {
+	cmd := newCommand("define-batch", "<file>", "Define and save all the words in a file.", 1, 1)
+	var opts batchOptions
+	cmd.flags.IntVar(&opts.concurrency, "concurrency", 4, "number of words defined in parallel")
+	cmd.flags.Float64Var(&opts.rate, "rate", 1, "maximum number of dictionaryapi.com and merriam-webster.com requests per second")
+	cmd.flags.StringVar(&opts.checkpoint, "checkpoint", "", "checkpoint file (default <file>.checkpoint)")
+	cmd.flags.StringVar(&opts.report, "report", "", "failure report file (default <file>.failures.json)")
+	cmd.flags.StringVar(&opts.format, "format", "", "input format: "+strings.Join(importer.Formats(), ", ")+" (default detected from the file name)")
+	cmd.run = func(ctx context.Context, app *app, args []string) error {
+		return defineBatch(ctx, app, args[0], opts)
+	}
+	return cmd
+}
+
+func refreshCommand() *command {
+	cmd := newCommand("refresh", "<word>", "Fetch the definition of a saved word again and update it.", 1, 1)
+	full, short := modeFlags(cmd)
+	cmd.run = func(ctx context.Context, app *app, args []string) error {
+		if err := app.setMode(*full, *short); err != nil {
+			return err
+		}
+		return refresh(ctx, app, args[0])
+	}
+	return cmd
+}
+
+func wodCommand() *command {
+	cmd := newCommand("wod", "[start-date [end-date]]", "Print the word of the day, today's by default.", 0, 2)
+	// The dictionary is opened by the command itself because most sources work without it.
+	cmd.noDictionary = true
+	var opts wodOptions
+	cmd.flags.BoolVar(&opts.define, "define", false, "define and save the words of the day instead of listing them")
+	cmd.flags.StringVar(&opts.last, "last", "", "the last `N` days or weeks up to today, e.g. 7d or 2w")
+	cmd.flags.StringVar(&opts.month, "month", "", "every day of a month (`YYYY-MM`)")
+	cmd.flags.BoolVar(&opts.yes, "yes", false, fmt.Sprintf("don't ask before fetching more than %d days", maxWodDays))
+	cmd.run = func(ctx context.Context, app *app, args []string) error {
+		return wordOfTheDay(ctx, app, args, opts)
+	}
+	return cmd
+}
+
+func statsCommand() *command {
+	cmd := newCommand("stats", "", "Print statistics about the lexicon.", 0, 0)
+	cmd.run = func(ctx context.Context, app *app, args []string) error {
+		return stats(ctx, app)
+	}
+	return cmd
+}
+
+func listCommand() *command {
+	cmd := newCommand("list", "", "List the words of the lexicon.", 0, 0)
+	tag := cmd.flags.String("tag", "", "only list words with this tag")
+	cmd.run = func(ctx context.Context, app *app, args []string) error {
+		return list(ctx, app, *tag)
+	}
+	return cmd
+}
+
+func searchCommand() *command {
+	cmd := newCommand("search", "<text>", "Search saved words by name, short definition, tag or note.", 1, 1)
+	cmd.run = func(ctx context.Context, app *app, args []string) error {
+		return search(ctx, app, args[0])
+	}
+	return cmd
+}
+
+func tagCommand() *command {
+	cmd := newCommand("tag", "<word> <tag>...", "Add tags to a saved word.", 2, -1)
+	remove := cmd.flags.Bool("remove", false, "remove the tags instead")
+	cmd.run = func(ctx context.Context, app *app, args []string) error {
+		return tag(ctx, app, args[0], args[1:], *remove)
+	}
+	return cmd
+}
+
+func noteCommand() *command {
+	cmd := newCommand("note", "<word> <text>...", "Append a note to a saved word.", 2, -1)
+	cmd.run = func(ctx context.Context, app *app, args []string) error {
+		return note(ctx, app, args[0], strings.Join(args[1:], " "))
+	}
+	return cmd
+}
+
+func exportCommand() *command {
+	cmd := newCommand("export", "", "Export the lexicon to other formats.", 0, 0)
+	var opts exportOptions
+	cmd.flags.StringVar(&opts.format, "format", exporter.JSONL, "export format: "+strings.Join(exporter.Formats(), ", "))
+	cmd.flags.StringVar(&opts.output, "output", "", "output file (default standard output, lexicon.apkg for anki)")
+	cmd.flags.StringVar(&opts.from, "from", "", "only words added on or after this date (YYYY-MM-DD)")
+	cmd.flags.StringVar(&opts.to, "to", "", "only words added on or before this date (YYYY-MM-DD)")
+	cmd.flags.StringVar(&opts.tag, "tag", "", "only words with this tag")
+	cmd.run = func(ctx context.Context, app *app, args []string) error {
+		return export(ctx, app.dictionary, app.location, opts)
+	}
+	return cmd
+}
+
+func removeCommand() *command {
+	cmd := newCommand("rm", "<word>", "Remove a word from the lexicon.", 1, 1)
+	cmd.run = func(ctx context.Context, app *app, args []string) error {
+		return remove(ctx, app, args[0])
+	}
+	return cmd
+}
+
+func configCommand() *command {
+	cmd := newCommand("config", "<get|set|show> [key [value]]", "Read or change the settings of the selected profile.", 1, 3)
+	cmd.noDictionary = true
+	cmd.run = func(ctx context.Context, app *app, args []string) error {
+		return configSettings(app, args)
+	}
+	return cmd
+}
+
+func transferCommand() *command {
+	cmd := newCommand("transfer", "", "Copy every word of the lexicon of a profile to another one.", 0, 0)
+	cmd.noDictionary = true
+	var opts transferOptions
+	cmd.flags.StringVar(&opts.from, "from", "", "`profile` to copy the words from, as set in the configuration file, without the environment variables")
+	cmd.flags.StringVar(&opts.to, "to", "", "`profile` to copy the words to, as set in the configuration file, without the environment variables")
+	cmd.flags.StringVar(&opts.onConflict, "on-conflict", conflictSkip, "what to do with words already in the destination: "+strings.Join(conflictPolicies, ", "))
+	cmd.run = func(ctx context.Context, app *app, args []string) error {
+		return transfer(ctx, app, opts)
+	}
+	return cmd
+}
+
+func dbCommand() *command {
+	return newGroup("db", "Check and maintain the SQLite database of the profile.",
+		doctorCommand(),
+		backupCommand(),
+		restoreCommand(),
+		mergeCommand(),
+	)
+}
+
+func backupCommand() *command {
+	cmd := newCommand("db backup", "", "Back up the database to a timestamped file, even while it's in use.", 0, 0)
+	cmd.noDictionary = true
+	var opts backupOptions
+	cmd.flags.StringVar(&opts.dir, "dir", "", "directory of the backups (default \"backups\" next to the database)")
+	cmd.flags.IntVar(&opts.keep, "keep", 10, "number of backups to keep, 0 keeps them all")
+	cmd.flags.BoolVar(&opts.gzip, "gzip", false, "compress the backup")
+	cmd.flags.BoolVar(&opts.encrypt, "encrypt", false, "encrypt the backup with a passphrase (env LEXICON_BACKUP_PASSPHRASE)")
+	cmd.run = func(ctx context.Context, app *app, args []string) error {
+		return backup(ctx, app, opts)
+	}
+	return cmd
+}
+
+func restoreCommand() *command {
+	cmd := newCommand("db restore", "<file>", "Replace the database with a backup, after backing it up.", 1, 1)
+	cmd.noDictionary = true
+	yes := cmd.flags.Bool("yes", false, "don't ask for confirmation")
+	cmd.run = func(ctx context.Context, app *app, args []string) error {
+		return restore(ctx, app, args[0], *yes)
+	}
+	return cmd
+}
+
+func mergeCommand() *command {
+	cmd := newCommand("db merge", "<other.sqlite>", "Merge the words of another lexicon database into this one.", 1, 1)
+	cmd.noDictionary = true
+	definition := cmd.flags.String("definition", lexdb.KeepNewest, "definition kept for words in both databases: "+strings.Join(lexdb.MergePolicies, ", "))
+	dryRun := cmd.flags.Bool("dry-run", false, "report what would change without changing anything")
+	cmd.run = func(ctx context.Context, app *app, args []string) error {
+		return mergeDB(ctx, app, args[0], *definition, *dryRun)
+	}
+	return cmd
+}
+
+func doctorCommand() *command {
+	cmd := newCommand("db doctor", "", "Check every word of the database for inconsistencies and optionally fix them.", 0, 0)
+	cmd.noDictionary = true
+	fix := cmd.flags.Bool("fix", false, "fix the problems instead of only reporting them")
+	cmd.run = func(ctx context.Context, app *app, args []string) error {
+		return doctor(ctx, app, *fix)
+	}
+	return cmd
+}
+
+func completionCommand() *command {
+	cmd := newCommand("completion", "<bash|zsh|fish>", "Print the shell completion script.", 1, 1)
+	cmd.noDictionary = true
+	cmd.run = func(ctx context.Context, app *app, args []string) error {
+		script, err := completionScript(args[0], app)
+		if err != nil {
+			return err
+		}
+		fmt.Print(script)
+		return nil
+	}
+	return cmd
+}
+
+func helpCommand() *command {
+	cmd := newCommand("help", "[command [subcommand]]", "Print the usage of the lexicon or a command.", 0, 2)
+	cmd.noDictionary = true
+	cmd.run = func(ctx context.Context, app *app, args []string) error {
+		if len(args) == 0 {
+			app.usage(os.Stdout)
+			return nil
+		}
+		c := app.find(args[0])
+		if c == nil {
+			return usageError{fmt.Sprintf("unknown command %q", args[0])}
+		}
+		c, rest := c.resolve(args[1:])
+		if len(rest) > 0 {
+			return usageError{fmt.Sprintf("unknown command %q", strings.Join(args, " "))}
+		}
+		c.usage(os.Stdout)
+		return nil
+	}
+	return cmd
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"lexicon/config"
+	"sort"
+	"strings"
+)
+
+// completionScript returns the completion script of the lexicon for shell. The scripts complete the
+// command names and the flags of each command.
+func completionScript(shell string, app *app) (string, error) {
+	switch shell {
+	case "bash":
+		return bashCompletion(app), nil
+	case "zsh":
+		return zshCompletion(app), nil
+	case "fish":
+		return fishCompletion(app), nil
+	}
+	return "", usageError{fmt.Sprintf("unsupported shell %q, expected bash, zsh or fish", shell)}
+}
+
+func commandNames(app *app) []string {
+	var names []string
+	for _, cmd := range app.commands {
+		names = append(names, cmd.name)
+	}
+	return names
+}
+
+// subcommandNames returns the names of the subcommands of cmd without the name of cmd.
+func subcommandNames(cmd *command) []string {
+	var names []string
+	for _, sub := range cmd.subcommands {
+		names = append(names, sub.shortName())
+	}
+	return names
+}
+
+// commandFlags returns the flags of cmd and its subcommands, which are completed together.
+func commandFlags(cmd *command) []*flag.Flag {
+	seen := make(map[string]bool)
+	var flags []*flag.Flag
+	for _, c := range append([]*command{cmd}, cmd.subcommands...) {
+		for _, name := range flagNames(c.flags) {
+			if !seen[name] {
+				seen[name] = true
+				flags = append(flags, c.flags.Lookup(name))
+			}
+		}
+	}
+	sort.Slice(flags, func(i, j int) bool { return flags[i].Name < flags[j].Name })
+	return flags
+}
+
+func dashed(names []string) []string {
+	var res []string
+	for _, n := range names {
+		res = append(res, "--"+n)
+	}
+	return res
+}
+
+func bashCompletion(app *app) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, `# bash completion for lexicon. Load it with:
+#   source <(lexicon completion bash)
+_lexicon() {
+    local cur="${COMP_WORDS[COMP_CWORD]}"
+    local cmd="" i
+    for ((i = 1; i < COMP_CWORD; i++)); do
+        case "${COMP_WORDS[i]}" in
+            --profile|-profile|--backend|-backend|--db|-db|--format|-format|--lines|-lines) ((i++)) ;;
+            -*) ;;
+            *) cmd="${COMP_WORDS[i]}"; break ;;
+        esac
+    done
+
+    local words
+    case "$cmd" in
+        "") words="%s %s" ;;
+`, strings.Join(commandNames(app), " "), strings.Join(dashed(flagNames(app.global)), " "))
+	for _, cmd := range app.commands {
+		var words []string
+		for _, f := range commandFlags(cmd) {
+			words = append(words, "--"+f.Name)
+		}
+		words = append(words, subcommandNames(cmd)...)
+		if cmd.name == "help" {
+			words = append(words, commandNames(app)...)
+		}
+		if cmd.name == "completion" {
+			words = append(words, "bash", "zsh", "fish")
+		}
+		if cmd.name == "config" {
+			words = append(words, "get", "set", "show")
+		}
+		fmt.Fprintf(&b, "        %s) words=%q ;;\n", cmd.name, strings.Join(words, " "))
+	}
+	b.WriteString(`    esac
+    COMPREPLY=($(compgen -W "$words" -- "$cur"))
+}
+complete -o default -F _lexicon lexicon
+`)
+	return b.String()
+}
+
+func zshCompletion(app *app) string {
+	var b strings.Builder
+	b.WriteString(`#compdef lexicon
+# zsh completion for lexicon. Load it with:
+#   source <(lexicon completion zsh)
+_lexicon() {
+    local -a commands
+    commands=(
+`)
+	for _, cmd := range app.commands {
+		fmt.Fprintf(&b, "        %s\n", zshQuote(cmd.name+":"+cmd.short))
+	}
+	b.WriteString(`    )
+
+    local i cmd=""
+    for ((i = 2; i < CURRENT; i++)); do
+        case "${words[i]}" in
+            --profile|-profile|--backend|-backend|--db|-db|--format|-format|--lines|-lines) ((i++)) ;;
+            -*) ;;
+            *) cmd="${words[i]}"; break ;;
+        esac
+    done
+
+    if [[ -z "$cmd" ]]; then
+        _arguments \
+`)
+	for _, name := range flagNames(app.global) {
+		f := app.global.Lookup(name)
+		fmt.Fprintf(&b, "            %s \\\n", zshQuote("--"+name+"["+f.Usage+"]:value:"))
+	}
+	b.WriteString(`            '*::command:->command'
+        _describe 'command' commands
+        return
+    fi
+
+    case "$cmd" in
+`)
+	for _, cmd := range app.commands {
+		var specs []string
+		for _, f := range commandFlags(cmd) {
+			specs = append(specs, zshQuote("--"+f.Name+"["+f.Usage+"]:value:"))
+		}
+		if len(cmd.subcommands) > 0 {
+			specs = append(specs, "'1:command:("+strings.Join(subcommandNames(cmd), " ")+")'")
+		}
+		switch cmd.name {
+		case "help":
+			specs = append(specs, "'1:command:("+strings.Join(commandNames(app), " ")+")'")
+		case "completion":
+			specs = append(specs, "'1:shell:(bash zsh fish)'")
+		case "config":
+			specs = append(specs, "'1:action:(get set show)'", "'2:key:("+strings.Join(config.Keys(), " ")+")'")
+		case "define-batch":
+			specs = append(specs, "'1:file:_files'")
+		}
+		if len(specs) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "        %s) _arguments %s ;;\n", cmd.name, strings.Join(specs, " "))
+	}
+	b.WriteString(`    esac
+}
+compdef _lexicon lexicon
+`)
+	return b.String()
+}
+
+// zshQuote quotes s with single quotes and escapes the characters _arguments treats specially.
+func zshQuote(s string) string {
+	s = strings.NewReplacer("'", `'\''`).Replace(s)
+	return "'" + s + "'"
+}
+
+func fishCompletion(app *app) string {
+	var b strings.Builder
+	b.WriteString(`# fish completion for lexicon. Load it with:
+#   lexicon completion fish | source
+complete -c lexicon -f
+`)
+	for _, name := range flagNames(app.global) {
+		f := app.global.Lookup(name)
+		fmt.Fprintf(&b, "complete -c lexicon -n __fish_use_subcommand -l %s -r -d %s\n", name, fishQuote(f.Usage))
+	}
+	for _, cmd := range app.commands {
+		fmt.Fprintf(&b, "complete -c lexicon -n __fish_use_subcommand -a %s -d %s\n", cmd.name, fishQuote(cmd.short))
+	}
+	for _, cmd := range app.commands {
+		cond := "'__fish_seen_subcommand_from " + cmd.name + "'"
+		for _, f := range commandFlags(cmd) {
+			fmt.Fprintf(&b, "complete -c lexicon -n %s -l %s -r -d %s\n", cond, f.Name, fishQuote(f.Usage))
+		}
+		for _, sub := range cmd.subcommands {
+			fmt.Fprintf(&b, "complete -c lexicon -n %s -a %s -d %s\n", cond, sub.shortName(), fishQuote(sub.short))
+		}
+		switch cmd.name {
+		case "help":
+			fmt.Fprintf(&b, "complete -c lexicon -n %s -a %q\n", cond, strings.Join(commandNames(app), " "))
+		case "completion":
+			fmt.Fprintf(&b, "complete -c lexicon -n %s -a 'bash zsh fish'\n", cond)
+		case "config":
+			fmt.Fprintf(&b, "complete -c lexicon -n %s -a 'get set show'\n", cond)
+		case "define-batch":
+			fmt.Fprintf(&b, "complete -c lexicon -n %s -F\n", cond)
+		}
+	}
+	return b.String()
+}
+
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(s) + "'"
+}
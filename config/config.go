@@ -0,0 +1,390 @@
+// config reads and writes the lexicon configuration file. The file is written in TOML and holds
+// named profiles, e.g. one for a local SQLite database and another one for the API:
+//
+//	default_profile = "home"
+//
+//	[profiles.home]
+//	backend = "sqlite"
+//	db = "/home/me/lexicon.sqlite"
+//
+//	[profiles.work]
+//	backend = "api"
+//	timezone = "Europe/Madrid"
+//
+// Environment variables override the settings of the file.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"lexicon/render"
+	"lexicon/util"
+	"lexicon/wod"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// DefaultProfileName is the profile used when no profile is selected.
+const DefaultProfileName = "default"
+
+// Print modes.
+const (
+	ModeShort = "short"
+	ModeFull  = "full"
+)
+
+// Providers.
+const (
+	DictionaryAPI  = "dictionaryapi.com"
+	MerriamWebster = "merriam-webster"
+)
+
+// Profile holds the settings of a profile.
+type Profile struct {
+	Backend              string   `toml:"backend,omitempty"`                // sqlite, api or dir.
+	DB                   string   `toml:"db,omitempty"`                     // SQLite database file, or directory of the dir backend.
+	APIKey               string   `toml:"api_key,omitempty"`                // Key of the lexicon API.
+	DictionaryAPIKey     string   `toml:"dictionary_api_key,omitempty"`     // Key of dictionaryapi.com.
+	DictionaryAPIURL     string   `toml:"dictionary_api_url,omitempty"`     // Address of the dictionaryapi.com references, the real one if empty.
+	MerriamWebsterCookie string   `toml:"merriam_webster_cookie,omitempty"` // Session cookie of merriam-webster.com.
+	Format               string   `toml:"format,omitempty"`                 // Output format.
+	Timezone             string   `toml:"timezone,omitempty"`               // IANA name of the display time zone, the system one if empty.
+	DateFormat           string   `toml:"date_format,omitempty"`            // Go layout of displayed dates, e.g. "02 Jan 2006 15:04".
+	AbridgeLines         int      `toml:"abridge_lines,omitzero"`           // Lines after which short output is cut, 0 fits the terminal.
+	Color                string   `toml:"color,omitempty"`                  // auto, always or never.
+	Providers            []string `toml:"providers,omitempty"`              // Services used to define and save words.
+	WodSource            string   `toml:"wod_source,omitempty"`             // Word of the day source: merriam-webster, lexicon or the URL of a wod service.
+	Renderer             string   `toml:"renderer,omitempty"`               // Layout of entries in the text format.
+	Template             string   `toml:"template,omitempty"`               // text/template file that replaces the renderer.
+	Mode                 string   `toml:"mode,omitempty"`                   // short or full definitions by default.
+}
+
+// Config is the content of the configuration file.
+type Config struct {
+	DefaultProfile string              `toml:"default_profile,omitempty"`
+	Profiles       map[string]*Profile `toml:"profiles,omitempty"`
+}
+
+// Defaults returns the settings used when neither the file nor the environment set them.
+func Defaults() Profile {
+	return Profile{
+		Backend:    "sqlite",
+		Format:     "text",
+		DateFormat: "2006-01-02 15:04:05",
+		Color:      "auto",
+		Providers:  []string{DictionaryAPI, MerriamWebster},
+		WodSource:  "https://rafaelrendon.io/wod",
+		Renderer:   render.Terminal,
+		Mode:       ModeShort,
+	}
+}
+
+// envVars maps setting keys to the environment variables that override them.
+var envVars = map[string]string{
+	"backend":                "DATA_SOURCE_TYPE",
+	"db":                     "DATA_SOURCE_NAME",
+	"api_key":                "API_KEY",
+	"dictionary_api_key":     "DICTIONARY_API_KEY",
+	"dictionary_api_url":     "DICTIONARY_API_URL",
+	"merriam_webster_cookie": "MERRIAM_WEBSTER_COOKIE",
+	"format":                 "LEXICON_FORMAT",
+	"timezone":               "LEXICON_TIMEZONE",
+	"date_format":            "LEXICON_DATE_FORMAT",
+	"abridge_lines":          "LEXICON_ABRIDGE_LINES",
+	"color":                  "LEXICON_COLOR",
+	"providers":              "LEXICON_PROVIDERS",
+	"wod_source":             "LEXICON_WOD_SOURCE",
+	"renderer":               "LEXICON_RENDERER",
+	"template":               "LEXICON_TEMPLATE",
+	"mode":                   "LEXICON_MODE",
+}
+
+// secrets are the keys whose values are masked by Show.
+var secrets = map[string]bool{
+	"api_key":                true,
+	"dictionary_api_key":     true,
+	"merriam_webster_cookie": true,
+}
+
+// Path returns the location of the configuration file: $LEXICON_CONFIG if set, otherwise
+// lexicon/config.toml under the user's configuration directory ($XDG_CONFIG_HOME or ~/.config on
+// Linux).
+func Path() (string, error) {
+	if p := os.Getenv("LEXICON_CONFIG"); p != "" {
+		return p, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "lexicon", "config.toml"), nil
+}
+
+// Load reads the configuration file in path. A missing file is an empty configuration.
+func Load(path string) (*Config, error) {
+	cfg := &Config{Profiles: make(map[string]*Profile)}
+	if _, err := toml.DecodeFile(path, cfg); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return cfg, nil
+		}
+		return nil, fmt.Errorf("unable to read %s: %s", path, err)
+	}
+	if cfg.Profiles == nil {
+		cfg.Profiles = make(map[string]*Profile)
+	}
+	return cfg, nil
+}
+
+// Save writes the configuration to path. The file is only readable by the user because it may
+// contain API keys.
+func (c *Config) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".config-*.toml")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := toml.NewEncoder(tmp).Encode(c); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// ProfileName returns the name of the profile to use: name if not empty, otherwise the default
+// profile of the file.
+func (c *Config) ProfileName(name string) string {
+	if name != "" {
+		return name
+	}
+	if c.DefaultProfile != "" {
+		return c.DefaultProfile
+	}
+	return DefaultProfileName
+}
+
+// Resolve returns the effective settings of a profile: the defaults, overridden by the profile and
+// then by the environment. It's an error to select a profile that doesn't exist, except the default
+// one.
+func (c *Config) Resolve(name string) (Profile, error) {
+	res, err := c.stored(name)
+	if err != nil {
+		return Profile{}, err
+	}
+	env, err := fromEnv()
+	if err != nil {
+		return Profile{}, err
+	}
+	res.Merge(env)
+	return res, res.Validate()
+}
+
+// Stored returns the settings of a profile as the file sets them, over the defaults. Unlike
+// Resolve, the environment is ignored, so that two profiles can be used at the same time.
+func (c *Config) Stored(name string) (Profile, error) {
+	res, err := c.stored(name)
+	if err != nil {
+		return Profile{}, err
+	}
+	return res, res.Validate()
+}
+
+func (c *Config) stored(name string) (Profile, error) {
+	name = c.ProfileName(name)
+	p, ok := c.Profiles[name]
+	if !ok && name != DefaultProfileName && name != c.DefaultProfile {
+		return Profile{}, fmt.Errorf("unknown profile %q", name)
+	}
+	res := Defaults()
+	if p != nil {
+		res.Merge(*p)
+	}
+	return res, nil
+}
+
+// fromEnv returns the settings set in the environment.
+func fromEnv() (Profile, error) {
+	var p Profile
+	for key, env := range envVars {
+		if v := os.Getenv(env); v != "" {
+			if key == "backend" {
+				// DATA_SOURCE_TYPE has historically been "API".
+				v = strings.ToLower(v)
+			}
+			if err := p.Set(key, v); err != nil {
+				return Profile{}, fmt.Errorf("invalid %s: %s", env, err)
+			}
+		}
+	}
+	return p, nil
+}
+
+// Merge overrides the settings of p with the non-zero settings of o.
+func (p *Profile) Merge(o Profile) {
+	dst := reflect.ValueOf(p).Elem()
+	src := reflect.ValueOf(o)
+	for i := 0; i < src.NumField(); i++ {
+		if !src.Field(i).IsZero() {
+			dst.Field(i).Set(src.Field(i))
+		}
+	}
+}
+
+// Validate checks the values of the settings that only accept a few values.
+func (p *Profile) Validate() error {
+	switch p.Backend {
+	case "sqlite", "api", "dir":
+	default:
+		return fmt.Errorf("invalid backend %q, expected sqlite, api or dir", p.Backend)
+	}
+	switch p.Color {
+	case "auto", "always", "never":
+	default:
+		return fmt.Errorf("invalid color %q, expected auto, always or never", p.Color)
+	}
+	if _, err := p.Location(); err != nil {
+		return fmt.Errorf("invalid timezone %q: %s", p.Timezone, err)
+	}
+	if strings.TrimSpace(p.DateFormat) == "" {
+		return errors.New("invalid date_format, it can't be blank")
+	}
+	if p.Mode != ModeShort && p.Mode != ModeFull {
+		return fmt.Errorf("invalid mode %q, expected short or full", p.Mode)
+	}
+	if !util.Contains(render.Names(), p.Renderer) {
+		return fmt.Errorf("invalid renderer %q, expected one of: %s", p.Renderer, strings.Join(render.Names(), ", "))
+	}
+	if !wod.IsSource(p.WodSource) {
+		return fmt.Errorf("invalid wod_source %q, expected %s, %s or the URL of a wod service", p.WodSource, wod.MerriamWebster, wod.Lexicon)
+	}
+	if p.AbridgeLines < 0 {
+		return fmt.Errorf("invalid abridge_lines %d", p.AbridgeLines)
+	}
+	return nil
+}
+
+// Location returns the display time zone: the one named by timezone, or the local zone of the
+// system if it's empty.
+func (p *Profile) Location() (*time.Location, error) {
+	if p.Timezone == "" {
+		return time.Local, nil
+	}
+	return time.LoadLocation(p.Timezone)
+}
+
+// HasProvider reports whether a provider is enabled.
+func (p *Profile) HasProvider(name string) bool {
+	for _, e := range p.Providers {
+		if e == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Keys returns the keys of the settings sorted alphabetically.
+func Keys() []string {
+	var keys []string
+	t := reflect.TypeOf(Profile{})
+	for i := 0; i < t.NumField(); i++ {
+		keys = append(keys, tomlKey(t.Field(i)))
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// EnvVar returns the environment variable that overrides key.
+func EnvVar(key string) string {
+	return envVars[key]
+}
+
+func tomlKey(f reflect.StructField) string {
+	return strings.Split(f.Tag.Get("toml"), ",")[0]
+}
+
+func (p *Profile) field(key string) (reflect.Value, error) {
+	v := reflect.ValueOf(p).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		if tomlKey(v.Type().Field(i)) == key {
+			return v.Field(i), nil
+		}
+	}
+	return reflect.Value{}, fmt.Errorf("unknown setting %q, valid settings: %s", key, strings.Join(Keys(), ", "))
+}
+
+// Get returns the value of a setting as a string. Lists are separated by commas.
+func (p *Profile) Get(key string) (string, error) {
+	f, err := p.field(key)
+	if err != nil {
+		return "", err
+	}
+	switch f.Kind() {
+	case reflect.String:
+		return f.String(), nil
+	case reflect.Int:
+		return strconv.FormatInt(f.Int(), 10), nil
+	case reflect.Slice:
+		return strings.Join(f.Interface().([]string), ","), nil
+	}
+	return "", fmt.Errorf("unsupported setting %q", key)
+}
+
+// Set parses value and assigns it to a setting. Lists are separated by commas.
+func (p *Profile) Set(key, value string) error {
+	f, err := p.field(key)
+	if err != nil {
+		return err
+	}
+	switch f.Kind() {
+	case reflect.String:
+		f.SetString(value)
+	case reflect.Int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%s must be a number", key)
+		}
+		f.SetInt(int64(n))
+	case reflect.Slice:
+		var values []string
+		for _, v := range strings.Split(value, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
+		}
+		f.Set(reflect.ValueOf(values))
+	default:
+		return fmt.Errorf("unsupported setting %q", key)
+	}
+	return nil
+}
+
+// Show returns the settings as "key = value" lines, masking secrets.
+func (p *Profile) Show() string {
+	var b strings.Builder
+	for _, key := range Keys() {
+		v, _ := p.Get(key)
+		if secrets[key] && v != "" {
+			v = "********"
+		}
+		fmt.Fprintf(&b, "%s = %q\n", key, v)
+	}
+	return b.String()
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"lexicon/lexdb"
+	"lexicon/util"
+	"log"
+	"os"
+	"strings"
+)
+
+// openDB opens the SQLite database of the profile, whatever its backend.
+func openDB(app *app) (*lexdb.Lexicon, error) {
+	if app.profile.DB == "" {
+		return nil, errors.New("no SQLite database, set db in the profile or DATA_SOURCE_NAME")
+	}
+	if app.profile.Backend == backendDir {
+		return nil, errors.New("the profile keeps its words in a directory, not in a SQLite database")
+	}
+	return lexdb.Open(app.profile.DB)
+}
+
+// doctor reports the inconsistencies of the database and, if fix is set, fixes them. Definitions
+// that can't be used are fetched again.
+func doctor(ctx context.Context, app *app, fix bool) error {
+	x, err := openDB(app)
+	if err != nil {
+		return err
+	}
+	defer x.Close()
+
+	problems, n, err := x.Check(ctx)
+	if err != nil {
+		return err
+	}
+	if !fix {
+		fixable := 0
+		for _, p := range problems {
+			if p.Kind == lexdb.BadDefinition {
+				p.Fix = "fetch the definition again"
+			}
+			if p.Fix != "" {
+				fixable++
+			}
+			printProblem(p)
+		}
+		fmt.Printf("Checked %d words, found %d problems.", n, len(problems))
+		if fixable > 0 {
+			fmt.Printf(" Run with --fix to fix %d of them.", fixable)
+		}
+		fmt.Println()
+		return nil
+	}
+
+	fixed, err := x.Repair(ctx)
+	if err != nil {
+		return err
+	}
+	for _, p := range fixed {
+		fmt.Printf("Fixed %q: %s\n", p.Name, p.Fix)
+	}
+
+	// Renamed and merged words are checked again before their definitions are fetched.
+	problems, _, err = x.Check(ctx)
+	if err != nil {
+		return err
+	}
+	failed := 0
+	for _, p := range problems {
+		if p.Kind != lexdb.BadDefinition {
+			continue
+		}
+		if err := refetch(ctx, x, p.Name); err != nil {
+			log.Printf("Unable to fetch the definition of %q: %s", p.Name, err)
+			failed++
+			continue
+		}
+		fmt.Printf("Fixed %q: fetched the definition again\n", p.Name)
+	}
+	for _, p := range problems {
+		if p.Kind != lexdb.BadDefinition {
+			printProblem(p)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d definitions couldn't be fetched", failed)
+	}
+	return nil
+}
+
+func printProblem(p lexdb.Problem) {
+	fix := p.Fix
+	if fix == "" {
+		fix = "can't be fixed automatically"
+	}
+	fmt.Printf("%q [%s]: %s (%s)\n", p.Name, p.Kind, p.Detail, fix)
+}
+
+// refetch replaces the definition of a saved word with a fresh one.
+func refetch(ctx context.Context, x *lexdb.Lexicon, name string) error {
+	lexeme, err := x.Find(ctx, name)
+	if err != nil {
+		return err
+	}
+	fresh, err := fetchDefinition(ctx, name)
+	if err != nil {
+		return err
+	}
+	lexeme.Definition = fresh.Definition
+	lexeme.Source = fresh.Source
+	return x.Update(ctx, lexeme)
+}
+
+// mergeDB merges the SQLite lexicon database in path into the database of the profile and prints
+// what changed. The database is backed up first, unless dryRun is set.
+func mergeDB(ctx context.Context, app *app, path, policy string, dryRun bool) error {
+	if !util.Contains(lexdb.MergePolicies, policy) {
+		return usageError{fmt.Sprintf("unknown definition policy %q, expected %s", policy, strings.Join(lexdb.MergePolicies, ", "))}
+	}
+	x, err := openDB(app)
+	if err != nil {
+		return err
+	}
+	defer x.Close()
+	if samePath(path, lexdb.FilePath(app.profile.DB)) {
+		return fmt.Errorf("%s is the database of the profile", path)
+	}
+
+	if err := lexdb.Verify(ctx, path); err != nil {
+		return err
+	}
+
+	if !dryRun {
+		previous, err := snapshot(ctx, app, x.Backup, "")
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Backed up the current database to %s\n", previous)
+	}
+	report, err := x.Merge(ctx, path, policy, dryRun)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range report.Added {
+		fmt.Printf("Added %q\n", name)
+	}
+	for _, c := range report.Conflicts {
+		fmt.Printf("%q %s: %s\n", c.Name, c.Field, c.Resolution)
+	}
+	fmt.Printf("Merged %s: %d words added, %d updated, %d unchanged, %d words of the day added.\n",
+		path, len(report.Added), len(report.Updated), report.Unchanged, report.Wods)
+	if dryRun {
+		fmt.Println("Dry run, nothing was changed.")
+	}
+	return nil
+}
+
+// samePath reports whether two paths name the same file.
+func samePath(a, b string) bool {
+	ia, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	ib, err := os.Stat(b)
+	return err == nil && os.SameFile(ia, ib)
+}
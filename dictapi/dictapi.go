@@ -2,6 +2,7 @@
 package dictapi
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -30,8 +31,8 @@ type DMeta struct {
 }
 
 type MDef struct {
-	Vd   string            `json:"vd"`
-	Sseq [][][]interface{} `json:"sseq"`
+	Vd   string `json:"vd"`
+	Sseq Sseq   `json:"sseq"`
 }
 
 type DQuote struct {
@@ -64,12 +65,32 @@ type DCxs struct {
 	} `json:"cxtis"`
 }
 
+// DVr is a variant spelling.
+type DVr struct {
+	Vl  string `json:"vl"`
+	Va  string `json:"va"`
+	Prs []DPrs `json:"prs"`
+}
+
+// DParagraph is a usage paragraph or a synonym discussion. The paragraph text holds text and vis
+// elements like a defining text.
+type DParagraph struct {
+	Pl string `json:"pl"`
+	Pt Dt     `json:"pt"`
+}
+
 type DEntry struct {
 	Meta     DMeta           `json:"meta"`
 	Hwi      DHwi            `json:"hwi"`
+	Ahws     []DHwi          `json:"ahws,omitempty"`
+	Vrs      []DVr           `json:"vrs,omitempty"`
 	Cxs      []DCxs          `json:"cxs"`
 	Fl       string          `json:"fl"`
+	Lbs      []string        `json:"lbs,omitempty"`
+	Sls      []string        `json:"sls,omitempty"`
 	Def      []MDef          `json:"def"`
+	Usages   []DParagraph    `json:"usages,omitempty"`
+	Syns     []DParagraph    `json:"syns,omitempty"`
 	Quotes   []DQuote        `json:"quotes,omitempty"`
 	Et       [][]interface{} `json:"et,omitempty"`
 	Date     string          `json:"date,omitempty"`
@@ -96,25 +117,102 @@ type DEntry struct {
 			Va string `json:"va"`
 		} `json:"vrs"`
 		Def []struct {
-			Sseq [][][]interface{} `json:"sseq"`
+			Sseq Sseq `json:"sseq"`
 		} `json:"def"`
 	} `json:"dros,omitempty"`
 }
 
 type GetDefinitionResult []DEntry
 
+// APIKey is the dictionaryapi.com key. If empty, the DICTIONARY_API_KEY environment variable is used.
+var APIKey string
+
+// Cookie is the merriam-webster.com session cookie used to update the saved words. If empty, the
+// MERRIAM_WEBSTER_COOKIE environment variable is used.
+var Cookie string
+
+// DefaultEndpoint is the address of the dictionaryapi.com references.
+const DefaultEndpoint = "https://dictionaryapi.com/api/v3/references"
+
+// Endpoint is the address of the references queried by Define, e.g. that of a dictapitest server.
+// If empty, DefaultEndpoint is used.
+//
+// Endpoint, APIKey and Cookie are read by the functions of the package on every call. Tests that
+// set them must not call t.Parallel; parallel tests use a Client each instead.
+var Endpoint string
+
+// Client queries dictionaryapi.com and updates the merriam-webster.com word list with its own
+// settings. The zero value queries DefaultEndpoint without credentials.
+type Client struct {
+	Endpoint   string       // Address of the references, DefaultEndpoint if empty.
+	APIKey     string       // dictionaryapi.com key.
+	Cookie     string       // merriam-webster.com session cookie.
+	HTTPClient *http.Client // http.DefaultClient if nil.
+}
+
+// defaultClient returns a client with the settings of the package variables.
+func defaultClient() *Client {
+	return &Client{Endpoint: Endpoint, APIKey: getDictionaryApiKey(), Cookie: getCookie()}
+}
+
+func (c *Client) endpoint() string {
+	if c.Endpoint != "" {
+		return strings.TrimSuffix(c.Endpoint, "/")
+	}
+	return DefaultEndpoint
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return http.DefaultClient
+}
+
 func getDictionaryApiKey() string {
+	if APIKey != "" {
+		return APIKey
+	}
 	return os.Getenv("DICTIONARY_API_KEY")
 }
 
+func getCookie() string {
+	if Cookie != "" {
+		return Cookie
+	}
+	return os.Getenv("MERRIAM_WEBSTER_COOKIE")
+}
+
 var DefNotFound = errors.New("found no definitions")
 
+// SuggestionsError is returned by Define when the word isn't in the dictionary but there are
+// spelling suggestions for it.
+type SuggestionsError struct {
+	Suggestions []string
+}
+
+func (e *SuggestionsError) Error() string {
+	message := "The word you've entered isn't in the dictionary. Spelling suggestions:\n"
+	for _, s := range e.Suggestions {
+		message += fmt.Sprintf("• %s\n", s)
+	}
+	return message
+}
+
 func wodNotFound(date string) error {
 	return fmt.Errorf("word of the day for '%s' not found", date)
 }
 
-func Define(name string) (*types.Definition, error) {
-	key := getDictionaryApiKey()
+// Define queries the collegiate dictionary with the settings of the package variables.
+func Define(ctx context.Context, name string) (*types.Definition, error) {
+	return defaultClient().Define(ctx, name)
+}
+
+// Define queries the collegiate dictionary for name. It returns a *SuggestionsError if the word
+// isn't in the dictionary but there are spelling suggestions for it, and DefNotFound if there
+// aren't.
+func (c *Client) Define(ctx context.Context, name string) (*types.Definition, error) {
+	key := c.APIKey
 	if len(key) == 0 {
 		return nil, errors.New("missing API key")
 	}
@@ -122,10 +220,14 @@ func Define(name string) (*types.Definition, error) {
 	// url.QueryEscape() vs url. PathEscape().
 	// See https://stackoverflow.com/q/2678551/526189
 	u := fmt.Sprintf(
-		`https://dictionaryapi.com/api/v3/references/collegiate/json/%s?key=%s`,
-		url.PathEscape(name), url.QueryEscape(key),
+		`%s/collegiate/json/%s?key=%s`,
+		c.endpoint(), url.PathEscape(name), url.QueryEscape(key),
 	)
-	res, err := http.Get(u)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.httpClient().Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -141,43 +243,51 @@ func Define(name string) (*types.Definition, error) {
 		return nil, fmt.Errorf("service returned %s: %s", res.Status, body)
 	}
 
+	definition, warnings, err := Parse(body)
+	for _, w := range warnings {
+		log.Printf("Skipped part of the definition of %s: %s", name, w)
+	}
+	return definition, err
+}
+
+// Parse converts a response of the collegiate dictionary. It returns a *SuggestionsError if the
+// response holds spelling suggestions and DefNotFound if it's empty. The warnings describe the
+// parts of the response that were left out because they're unknown or malformed; only a response
+// that isn't an array fails.
+func Parse(body []byte) (*types.Definition, []string, error) {
 	ss := parseSpellingSuggestions(body)
 	if len(ss) > 0 {
-		message := "The word you've entered isn't in the dictionary. Spelling suggestions:\n"
-		for _, e := range ss {
-			message += fmt.Sprintf("• %s\n", e)
-		}
-		return nil, errors.New(message)
+		return nil, nil, &SuggestionsError{Suggestions: ss}
 	}
 
-	var data GetDefinitionResult
+	var data []json.RawMessage
 	if err := json.Unmarshal(body, &data); err != nil {
-		return nil, err
-	}
-	if len(data) < 1 {
-		return nil, DefNotFound
+		return nil, nil, err
 	}
+	var p parser
 	var definition types.Definition
-	for _, entry := range data {
-		definition.Entries = append(definition.Entries, parseEntry(entry))
+	for i, raw := range data {
+		var entry DEntry
+		if err := json.Unmarshal(raw, &entry); err != nil {
+			p.warn(fmt.Sprintf("entry[%d]", i), "invalid entry: %s", err)
+			continue
+		}
+		definition.Entries = append(definition.Entries, p.parseEntry(entry))
+	}
+	if len(definition.Entries) == 0 {
+		return nil, p.warnings, DefNotFound
 	}
-	return &definition, nil
+	return &definition, p.warnings, nil
 }
 
-var client *http.Client
-
-func post(u, name string) error {
-	cookie := os.Getenv("MERRIAM_WEBSTER_COOKIE")
+func (c *Client) post(ctx context.Context, u, name string) error {
+	cookie := c.Cookie
 	if cookie == "" {
 		return errors.New("missing Merriam-Webster cookie")
 	}
 
-	if client == nil {
-		client = &http.Client{}
-	}
-
 	payload := fmt.Sprintf("word=%s&type=d", url.QueryEscape(name))
-	req, err := http.NewRequest("POST", u, strings.NewReader(payload))
+	req, err := http.NewRequestWithContext(ctx, "POST", u, strings.NewReader(payload))
 	if err != nil {
 		return fmt.Errorf("unable to create request: %s", err)
 	}
@@ -186,7 +296,7 @@ func post(u, name string) error {
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=UTF-8")
 	req.Header.Set("Cookie", cookie)
 
-	res, err := client.Do(req)
+	res, err := c.httpClient().Do(req)
 	if err != nil {
 		return err
 	}
@@ -207,14 +317,27 @@ func post(u, name string) error {
 	return nil
 }
 
-func Save(name string) error {
+// Save adds name to the merriam-webster.com word list with the settings of the package variables.
+func Save(ctx context.Context, name string) error {
+	return defaultClient().Save(ctx, name)
+}
+
+// Remove removes name from the merriam-webster.com word list with the settings of the package
+// variables.
+func Remove(ctx context.Context, name string) error {
+	return defaultClient().Remove(ctx, name)
+}
+
+// Save adds name to the merriam-webster.com word list.
+func (c *Client) Save(ctx context.Context, name string) error {
 	u := "https://www.merriam-webster.com/lapi/v1/wordlist/save"
-	return post(u, name)
+	return c.post(ctx, u, name)
 }
 
-func Remove(name string) error {
+// Remove removes name from the merriam-webster.com word list.
+func (c *Client) Remove(ctx context.Context, name string) error {
 	u := "https://www.merriam-webster.com/lapi/v1/wordlist/delete"
-	return post(u, name)
+	return c.post(ctx, u, name)
 }
 
 // parseSpellingSuggestions tries to parse spelling suggestions, which is an array of strings. If
@@ -227,14 +350,32 @@ func parseSpellingSuggestions(buf []byte) []string {
 	return suggestions
 }
 
-func parseEntry(entry DEntry) types.Entry {
+// parser converts the entries of dictionaryapi.com and collects warnings about the parts it
+// doesn't know.
+type parser struct {
+	warnings []string
+}
+
+// warn adds a warning about the part of an entry at path, e.g. "ephemeral:1 def[0] sseq[2][0]".
+func (p *parser) warn(path, format string, args ...interface{}) {
+	p.warnings = append(p.warnings, path+": "+fmt.Sprintf(format, args...))
+}
+
+func (p *parser) parseEntry(entry DEntry) types.Entry {
+	id := entry.Meta.ID
 	return types.Entry{
 		Meta:                parseMeta(entry.Meta),
 		Headword:            parseHeadword(entry.Hwi),
+		AlternateHeadwords:  parseHeadwords(entry.Ahws),
+		Variants:            parseVariants(entry.Vrs),
 		Cognates:            parseCognates(entry.Cxs),
 		GrammaticalFunction: entry.Fl,
+		Labels:              entry.Lbs,
+		SubjectLabels:       entry.Sls,
 		ShortDefinitions:    entry.Shortdef,
-		Defs:                parseDefinitions(entry.Def),
+		Defs:                p.parseDefinitions(id, entry.Def),
+		Usages:              p.parseParagraphs(id+" usages", entry.Usages),
+		SynonymDiscussions:  p.parseParagraphs(id+" syns", entry.Syns),
 		Quotes:              parseQuotes(entry.Quotes),
 	}
 }
@@ -275,6 +416,37 @@ func parseHeadword(hwi DHwi) types.Headword {
 	}
 }
 
+func parseHeadwords(ahws []DHwi) []types.Headword {
+	var res []types.Headword
+	for _, hwi := range ahws {
+		res = append(res, parseHeadword(hwi))
+	}
+	return res
+}
+
+func parseVariants(vrs []DVr) []types.Variant {
+	var res []types.Variant
+	for _, v := range vrs {
+		res = append(res, types.Variant{
+			Label:          v.Vl,
+			Text:           v.Va,
+			Pronunciations: parsePronunciations(v.Prs),
+		})
+	}
+	return res
+}
+
+// parseParagraphs converts usage paragraphs or synonym discussions, whose text is read like a usage
+// note.
+func (p *parser) parseParagraphs(path string, paragraphs []DParagraph) []types.Paragraph {
+	var res []types.Paragraph
+	for i, para := range paragraphs {
+		text, vis := p.parseNote(fmt.Sprintf("%s[%d] pt", path, i), para.Pt)
+		res = append(res, types.Paragraph{Label: para.Pl, Text: text, VerbalIllustrations: vis})
+	}
+	return res
+}
+
 func parsePronunciations(prs []DPrs) []types.Pronunciation {
 	var res []types.Pronunciation
 	for _, p := range prs {
@@ -286,99 +458,181 @@ func parsePronunciations(prs []DPrs) []types.Pronunciation {
 	return res
 }
 
-func parseDefinitions(defs []MDef) []types.Def {
+func (p *parser) parseDefinitions(id string, defs []MDef) []types.Def {
 	var res []types.Def
-	for _, def := range defs {
-		res = append(res, parseDefinition(def))
+	for i, def := range defs {
+		res = append(res, p.parseDefinition(fmt.Sprintf("%s def[%d]", id, i), def))
 	}
 	return res
 }
 
 // parseDefinition parses definitions.
-func parseDefinition(def MDef) types.Def {
+func (p *parser) parseDefinition(path string, def MDef) types.Def {
 	return types.Def{
 		VerbDivider: def.Vd,
-		Senses:      parseSenses(def.Sseq),
+		Senses:      p.parseSenses(path, def.Sseq),
 	}
 }
 
-// Ref: https://dictionaryapi.com/products/json#sec-2.dt
-func parseSenses(sseq [][][]interface{}) []types.Sense {
+// parseSenses flattens a sense sequence, in order. Binding substitutes and the senses of
+// parenthesized sense sequences become senses of their own.
+// Ref: https://dictionaryapi.com/products/json#sec-2.sseq
+func (p *parser) parseSenses(path string, sseq Sseq) []types.Sense {
 	var senses []types.Sense
-	for _, a := range sseq {
-		for _, b := range a {
-			for _, c := range b {
-				if _, ok := c.(map[string]interface{}); ok {
-					var sense types.Sense
-					if s, isMap := c.(map[string]interface{}); isMap {
-						if s["dt"] == nil {
-							continue
-						}
-						dt, isArray := s["dt"].([]interface{})
-						if !isArray {
-							continue
-						}
-						sense.Text = extractText(dt, "text")
-						sense.UsageNotes = parseUsageNotes(dt)
-						sense.VerbalIllustrations = parseVerbalIllustrations(dt)
-					}
-					senses = append(senses, sense)
-				}
-			}
+	for i, group := range sseq {
+		for j, item := range group {
+			senses = p.parseSenseItem(fmt.Sprintf("%s sseq[%d][%d]", path, i, j), item, senses)
 		}
 	}
 	return senses
 }
 
-// extractText returns the first string in an array next to the "text" string.
-func extractText(i interface{}, key string) string {
-	if arr, isArray := i.([]interface{}); isArray {
-		if len(arr) >= 2 {
-			e0, ok0 := arr[0].(string)
-			e1, ok1 := arr[1].(string)
-			if ok0 && ok1 && e0 == key {
-				return e1
-			}
+func (p *parser) parseSenseItem(path string, item SenseItem, senses []types.Sense) []types.Sense {
+	if item.Err != nil {
+		p.warn(path, "%s", item.Err)
+		return senses
+	}
+	switch item.Type {
+	case "sense", "sen", "bs":
+		if item.Sense == nil {
+			return senses
 		}
-		for _, a := range arr {
-			if text := extractText(a, key); text != "" {
-				return text
-			}
+		s := item.Sense
+		senses = appendSense(senses, p.parseSense(path, s.Sn, "", s.Dt))
+		if sd := s.Sdsense; sd != nil {
+			senses = appendSense(senses, p.parseSense(path+" sdsense", "", sd.Sd+" ", sd.Dt))
 		}
+	case "pseq":
+		for i, e := range item.Pseq {
+			senses = p.parseSenseItem(fmt.Sprintf("%s pseq[%d]", path, i), e, senses)
+		}
+	default:
+		p.warn(path, "unknown sense type %q", item.Type)
+	}
+	return senses
+}
+
+// appendSense appends a sense unless it's empty, like the truncated senses that only have a number.
+func appendSense(senses []types.Sense, s types.Sense) []types.Sense {
+	if s.Text == "" && len(s.UsageNotes) == 0 && len(s.VerbalIllustrations) == 0 {
+		return senses
 	}
+	return append(senses, s)
+}
 
-	if mp, isMap := i.(map[string]interface{}); isMap {
-		for k, v := range mp {
-			if s, ok := v.(string); ok && k == key {
-				return s
+// parseSense converts the defining text of a sense, whose text starts with prefix, e.g. the
+// "specifically" of a divided sense.
+// Ref: https://dictionaryapi.com/products/json#sec-2.dt
+func (p *parser) parseSense(path, number, prefix string, dt Dt) types.Sense {
+	text := prefix
+	sense := types.Sense{Number: number}
+	for i, e := range dt {
+		path := fmt.Sprintf("%s dt[%d]", path, i)
+		if e.Err != nil {
+			p.warn(path, "%s", e.Err)
+			continue
+		}
+		switch e.Type {
+		case "text":
+			text += e.Text
+		case "vis":
+			sense.VerbalIllustrations = append(sense.VerbalIllustrations, parseVis(e.Vis)...)
+		case "uns":
+			for j, note := range e.Uns {
+				text, vis := p.parseNote(fmt.Sprintf("%s uns[%d]", path, j), note)
+				if text != "" {
+					sense.UsageNotes = append(sense.UsageNotes, text)
+				}
+				sense.VerbalIllustrations = append(sense.VerbalIllustrations, vis...)
+			}
+		case "snote":
+			text, vis := p.parseNote(path+" snote", e.Snote)
+			if text != "" {
+				sense.UsageNotes = append(sense.UsageNotes, text)
+			}
+			sense.VerbalIllustrations = append(sense.VerbalIllustrations, vis...)
+		case "ri":
+			text += p.parseRunIn(path, e.Ri)
+		case "bnw":
+			if e.Bnw != nil {
+				text += joinNonEmpty(" ", e.Bnw.Pname, e.Bnw.Sname, e.Bnw.Altname)
 			}
-			if text := extractText(v, key); text != "" {
-				return text
+		case "ca":
+			if e.Ca == nil {
+				continue
 			}
+			var cats []string
+			for _, c := range e.Ca.Cats {
+				cats = append(cats, c.Cat)
+			}
+			text = joinNonEmpty(" ", strings.TrimSpace(text), e.Ca.Intro, strings.Join(cats, ", "))
+		default:
+			p.warn(path, "unknown element %q", e.Type)
+		}
+	}
+	sense.Text = strings.TrimSpace(text)
+	return sense
+}
+
+// parseNote returns the text and the verbal illustrations of a usage or supplemental note.
+func (p *parser) parseNote(path string, note Dt) (string, []string) {
+	var text string
+	var vis []string
+	for i, e := range note {
+		path := fmt.Sprintf("%s[%d]", path, i)
+		if e.Err != nil {
+			p.warn(path, "%s", e.Err)
+			continue
+		}
+		switch e.Type {
+		case "text", "t":
+			text += e.Text
+		case "ri":
+			text += p.parseRunIn(path, e.Ri)
+		case "vis":
+			vis = append(vis, parseVis(e.Vis)...)
+		default:
+			p.warn(path, "unknown element %q", e.Type)
 		}
 	}
-	return ""
+	return strings.TrimSpace(text), vis
 }
 
-func parseUsageNotes(dt []interface{}) []string {
-	var notes []string
-	for _, e := range dt {
-		if x, isArray := e.([]interface{}); isArray && len(x) >= 2 && x[0] == "uns" {
-			notes = append(notes, extractText(x, "text"))
+// parseRunIn returns the text of run-in entries.
+func (p *parser) parseRunIn(path string, ri Dt) string {
+	var text string
+	for i, e := range ri {
+		switch {
+		case e.Err != nil:
+			p.warn(fmt.Sprintf("%s ri[%d]", path, i), "%s", e.Err)
+		case e.Type == "text":
+			text += e.Text
+		case e.Type == "riw" && e.Riw != nil:
+			text += e.Riw.Rie
+		default:
+			p.warn(fmt.Sprintf("%s ri[%d]", path, i), "unknown element %q", e.Type)
 		}
 	}
-	return notes
+	return text
 }
 
-func parseVerbalIllustrations(dt []interface{}) []string {
+func parseVis(vis []DQuote) []string {
 	var res []string
+	for _, v := range vis {
+		res = append(res, v.T)
+	}
+	return res
+}
 
-	for _, e := range dt {
-		if x, isArray := e.([]interface{}); isArray && len(x) >= 2 && x[0] == "vis" {
-			res = append(res, extractText(x, "t"))
+// joinNonEmpty joins the strings that aren't empty.
+func joinNonEmpty(sep string, s ...string) string {
+	var res []string
+	for _, e := range s {
+		if e != "" {
+			res = append(res, e)
 		}
 	}
-	return res
+	return strings.Join(res, sep)
 }
 
 func parseQuotes(quotes []DQuote) []types.Quote {
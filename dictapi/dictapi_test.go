@@ -0,0 +1,424 @@
+package dictapi_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"lexicon/dictapi"
+	"lexicon/dictapi/dictapitest"
+	"lexicon/types"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// entries defines word with c and returns the function and first short definition of every entry.
+func entries(c *dictapi.Client, word string) ([][2]string, error) {
+	d, err := c.Define(context.Background(), word)
+	if err != nil {
+		return nil, err
+	}
+	var got [][2]string
+	for _, e := range d.Entries {
+		var short string
+		if len(e.ShortDefinitions) > 0 {
+			short = e.ShortDefinitions[0]
+		}
+		got = append(got, [2]string{e.GrammaticalFunction, short})
+	}
+	return got, nil
+}
+
+func TestDefine(t *testing.T) {
+	srv := dictapitest.NewServer(dictapitest.Fixtures())
+	// The subtests run in parallel, after TestDefine returns.
+	t.Cleanup(srv.Close)
+	c := &dictapi.Client{Endpoint: srv.URL, APIKey: dictapitest.Key}
+
+	tests := []struct {
+		word  string
+		want  [][2]string
+		check func(err error) bool
+	}{
+		{word: "ephemeral", want: [][2]string{
+			{"adjective", "lasting a very short time"},
+			{"noun", "something that is ephemeral"},
+		}},
+		{word: "accept", want: [][2]string{{"verb", "to receive willingly"}}},
+		{word: "ephemerl", check: func(err error) bool {
+			var serr *dictapi.SuggestionsError
+			return errors.As(err, &serr) && len(serr.Suggestions) == 6 && serr.Suggestions[0] == "ephemera"
+		}},
+		{word: "qwzxv", check: func(err error) bool {
+			return errors.Is(err, dictapi.DefNotFound)
+		}},
+		{word: "unavailable", check: func(err error) bool {
+			return err != nil && strings.Contains(err.Error(), "503")
+		}},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.word, func(t *testing.T) {
+			t.Parallel()
+			got, err := entries(c, tt.word)
+			if tt.check != nil {
+				if !tt.check(err) {
+					t.Errorf("Define(%q) returned %v, %v", tt.word, got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Define(%q) returned %v", tt.word, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Define(%q) returned entries %q, want %q", tt.word, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDefineWithoutKey(t *testing.T) {
+	srv := dictapitest.NewServer(dictapitest.Fixtures())
+	defer srv.Close()
+
+	c := &dictapi.Client{Endpoint: srv.URL}
+	if _, err := c.Define(context.Background(), "accept"); err == nil {
+		t.Error("Define without API key succeeded")
+	}
+	c.APIKey = "wrong"
+	if _, err := c.Define(context.Background(), "accept"); err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("Define with the wrong API key returned %v, want 403 Forbidden", err)
+	}
+}
+
+// TestDefinePackage checks that Define uses the package variables. It must not run in parallel.
+func TestDefinePackage(t *testing.T) {
+	srv := dictapitest.NewServer(dictapitest.Fixtures())
+	defer srv.Close()
+
+	endpoint, key := dictapi.Endpoint, dictapi.APIKey
+	defer func() { dictapi.Endpoint, dictapi.APIKey = endpoint, key }()
+	dictapi.Endpoint, dictapi.APIKey = srv.URL+"/", dictapitest.Key
+
+	def, err := dictapi.Define(context.Background(), "accept")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(def.Entries) != 1 {
+		t.Errorf("Define returned %d entries, want 1", len(def.Entries))
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	const apiKey = "upstream-key"
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("key") != apiKey {
+			http.Error(w, "invalid key", http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/collegiate/json/ephemeral":
+			body, err := fs.ReadFile(dictapitest.Fixtures(), "collegiate/ephemeral.json")
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write(body)
+		case "/collegiate/json/qwzxv":
+			_, _ = w.Write([]byte("[]"))
+		default:
+			http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
+		}
+	}))
+	defer upstream.Close()
+
+	dir := t.TempDir()
+	recorder := httptest.NewServer(dictapitest.Recorder(dir, upstream.URL, apiKey))
+	defer recorder.Close()
+
+	c := &dictapi.Client{Endpoint: recorder.URL, APIKey: dictapitest.Key}
+	recorded := make(map[string]error)
+	for _, word := range []string{"ephemeral", "qwzxv", "unavailable"} {
+		_, recorded[word] = entries(c, word)
+	}
+	if err := recorded["ephemeral"]; err != nil {
+		t.Errorf("Define(ephemeral) through the recorder returned %v", err)
+	}
+
+	for name, want := range map[string]string{
+		"ephemeral.status":   "",
+		"qwzxv.json":         "[]",
+		"qwzxv.status":       "",
+		"unavailable.json":   "Service Unavailable\n",
+		"unavailable.status": "503\n",
+	} {
+		b, err := os.ReadFile(filepath.Join(dir, "collegiate", name))
+		if want == "" {
+			if !os.IsNotExist(err) {
+				t.Errorf("recorder wrote %s: %q, %v", name, b, err)
+			}
+			continue
+		}
+		if err != nil || string(b) != want {
+			t.Errorf("recorder wrote %s: %q, %v, want %q", name, b, err, want)
+		}
+	}
+	_ = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			if b, _ := os.ReadFile(path); strings.Contains(string(b), apiKey) {
+				t.Errorf("recorder wrote the API key to %s", path)
+			}
+		}
+		return err
+	})
+
+	// The recorded fixtures replay the same responses.
+	srv := dictapitest.NewServer(os.DirFS(dir))
+	defer srv.Close()
+	replay := &dictapi.Client{Endpoint: srv.URL, APIKey: dictapitest.Key}
+	want, _ := entries(c, "ephemeral")
+	got, err := entries(replay, "ephemeral")
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("replayed Define(ephemeral) returned %q, %v, want %q", got, err, want)
+	}
+	for _, word := range []string{"qwzxv", "unavailable"} {
+		_, err := entries(replay, word)
+		if err == nil || err.Error() != recorded[word].Error() {
+			t.Errorf("replayed Define(%s) returned %v, want %v", word, err, recorded[word])
+		}
+	}
+}
+
+// TestParseFixtures parses every fixture of the collegiate dictionary twice and checks that the
+// results are the same.
+func TestParseFixtures(t *testing.T) {
+	fsys := dictapitest.Fixtures()
+	names, err := fs.Glob(fsys, "collegiate/*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]struct {
+		entries int
+		err     func(error) bool
+	}{
+		"ephemeral": {entries: 2},
+		"accept":    {entries: 1},
+		"ephemerl": {err: func(err error) bool {
+			var serr *dictapi.SuggestionsError
+			return errors.As(err, &serr)
+		}},
+		"qwzxv": {err: func(err error) bool { return errors.Is(err, dictapi.DefNotFound) }},
+		// The body of the 503 response isn't JSON.
+		"unavailable": {err: func(err error) bool { return err != nil }},
+	}
+	for _, name := range names {
+		word := strings.TrimSuffix(path.Base(name), ".json")
+		w, ok := want[word]
+		if !ok {
+			t.Errorf("no expectation for fixture %s", name)
+			continue
+		}
+		body, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		def, warnings, err := dictapi.Parse(body)
+		if w.err != nil {
+			if !w.err(err) {
+				t.Errorf("Parse(%s) returned error %v", name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Parse(%s) returned %v", name, err)
+			continue
+		}
+		if len(def.Entries) != w.entries || len(warnings) > 0 {
+			t.Errorf("Parse(%s) returned %d entries and warnings %q, want %d entries and no warnings",
+				name, len(def.Entries), warnings, w.entries)
+		}
+		checkDeterministic(t, name, body)
+	}
+}
+
+// TestParseSections checks the parts of the fixtures besides the senses: usage paragraphs, synonym
+// discussions, variants, alternate headwords and labels.
+func TestParseSections(t *testing.T) {
+	parse := func(word string) []types.Entry {
+		body, err := fs.ReadFile(dictapitest.Fixtures(), "collegiate/"+word+".json")
+		if err != nil {
+			t.Fatal(err)
+		}
+		def, _, err := dictapi.Parse(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return def.Entries
+	}
+	accept, ephemeral := parse("accept")[0], parse("ephemeral")
+	adjective, noun := ephemeral[0], ephemeral[1]
+
+	if len(accept.Usages) != 1 || accept.Usages[0].Label != "Accept vs. Except" ||
+		!strings.HasPrefix(accept.Usages[0].Text, "{it}Accept{/it} and {it}except{/it}") {
+		t.Errorf("usages of accept are %+v", accept.Usages)
+	}
+	if syns := adjective.SynonymDiscussions; len(syns) != 1 || syns[0].Label != "synonyms" ||
+		!strings.Contains(syns[0].Text, "short-lived") ||
+		!reflect.DeepEqual(syns[0].VerbalIllustrations, []string{"{wi}ephemeral{/wi} pleasures"}) {
+		t.Errorf("synonym discussions of ephemeral are %+v", syns)
+	}
+	if want := []types.Variant{{Label: "less commonly", Text: "ephem*er*ous"}}; !reflect.DeepEqual(adjective.Variants, want) {
+		t.Errorf("variants of ephemeral are %+v, want %+v", adjective.Variants, want)
+	}
+	if want := []types.Headword{{Text: "ephem*er*on"}}; !reflect.DeepEqual(noun.AlternateHeadwords, want) {
+		t.Errorf("alternate headwords of ephemeral:2 are %+v, want %+v", noun.AlternateHeadwords, want)
+	}
+	if !reflect.DeepEqual(noun.Labels, []string{"often plural"}) || !reflect.DeepEqual(noun.SubjectLabels, []string{"botany"}) {
+		t.Errorf("labels of ephemeral:2 are %q and %q, want [often plural] and [botany]", noun.Labels, noun.SubjectLabels)
+	}
+	if len(accept.SynonymDiscussions) > 0 || len(adjective.Usages) > 0 || len(noun.Variants) > 0 || len(adjective.Labels) > 0 {
+		t.Error("Parse filled sections that aren't in the fixtures")
+	}
+}
+
+// TestParseMalformed checks that unknown and malformed parts of a response are left out with a
+// warning that has their path, and that the rest of the response is kept.
+func TestParseMalformed(t *testing.T) {
+	// entry returns an entry with the sense sequence sseq.
+	entry := func(id, sseq string) string {
+		return `{"meta":{"id":"` + id + `"},"fl":"noun","shortdef":["short"],"def":[{"sseq":` + sseq + `}]}`
+	}
+	good := `["sense",{"sn":"1","dt":[["text","good"]]}]`
+	tests := []struct {
+		name     string
+		body     string
+		senses   []string // Text of the senses of the first entry.
+		warnings []string // Prefixes of the warnings.
+		err      bool
+	}{
+		{
+			name:   "valid",
+			body:   `[` + entry("x", `[[`+good+`]]`) + `]`,
+			senses: []string{"good"},
+		},
+		{
+			name:     "unknown sense type",
+			body:     `[` + entry("x", `[[["xyz",{}],`+good+`]]`) + `]`,
+			senses:   []string{"good"},
+			warnings: []string{`x def[0] sseq[0][0]: unknown sense type "xyz"`},
+		},
+		{
+			name:     "unknown element",
+			body:     `[` + entry("x", `[[["sense",{"dt":[["zz",{}],["text","good"]]}]]]`) + `]`,
+			senses:   []string{"good"},
+			warnings: []string{`x def[0] sseq[0][0] dt[0]: unknown element "zz"`},
+		},
+		{
+			name:     "malformed sense",
+			body:     `[` + entry("x", `[[["sense",{"sn":"1","dt":"oops"}],`+good+`]]`) + `]`,
+			senses:   []string{"good"},
+			warnings: []string{`x def[0] sseq[0][0]: invalid sense: `},
+		},
+		{
+			name:     "malformed pseq",
+			body:     `[` + entry("x", `[[["pseq",[["bs",5],`+good+`]]]]`) + `]`,
+			senses:   []string{"good"},
+			warnings: []string{`x def[0] sseq[0][0] pseq[0]: invalid bs: `},
+		},
+		{
+			name:     "malformed text",
+			body:     `[` + entry("x", `[[["sense",{"dt":[["text",5],["text","good"]]}]]]`) + `]`,
+			senses:   []string{"good"},
+			warnings: []string{`x def[0] sseq[0][0] dt[0]: invalid text: `},
+		},
+		{
+			name:   "malformed usage note",
+			body:   `[` + entry("x", `[[["sense",{"dt":[["text","good"],["uns",[[["text","note"]],"oops"]]]}]]]`) + `]`,
+			senses: []string{"good"},
+			warnings: []string{
+				`x def[0] sseq[0][0] dt[1]: invalid uns: `,
+			},
+		},
+		{
+			name:   "malformed run-in",
+			body:   `[` + entry("x", `[[["sense",{"dt":[["text","good"],["ri",[["riw",[]],["text"," ok"]]]]}]]]`) + `]`,
+			senses: []string{"good ok"},
+			warnings: []string{
+				`x def[0] sseq[0][0] dt[1] ri[0]: invalid riw: `,
+			},
+		},
+		{
+			name:     "not a pair",
+			body:     `[` + entry("x", `[[["text"],"sense",`+good+`]]`) + `]`,
+			senses:   []string{"good"},
+			warnings: []string{`x def[0] sseq[0][0]: expected a [type, value] pair`, `x def[0] sseq[0][1]: expected a [type, value] pair`},
+		},
+		{
+			name:     "malformed entry",
+			body:     `[{"meta":"oops"},` + entry("x", `[[`+good+`]]`) + `]`,
+			senses:   []string{"good"},
+			warnings: []string{`entry[0]: invalid entry: `},
+		},
+		{
+			name:     "only malformed entries",
+			body:     `[{"meta":"oops"}]`,
+			warnings: []string{`entry[0]: invalid entry: `},
+			err:      true,
+		},
+		{name: "object", body: `{"meta":{"id":"x"}}`, err: true},
+		{name: "not JSON", body: `Service Unavailable`, err: true},
+	}
+	for _, tt := range tests {
+		def, warnings, err := dictapi.Parse([]byte(tt.body))
+		if (err != nil) != tt.err {
+			t.Errorf("%s: Parse returned error %v", tt.name, err)
+			continue
+		}
+		if len(warnings) != len(tt.warnings) {
+			t.Errorf("%s: Parse returned warnings %q, want %q", tt.name, warnings, tt.warnings)
+		} else {
+			for i, w := range warnings {
+				if !strings.HasPrefix(w, tt.warnings[i]) {
+					t.Errorf("%s: warning %q doesn't start with %q", tt.name, w, tt.warnings[i])
+				}
+			}
+		}
+		if err != nil {
+			continue
+		}
+		var senses []string
+		for _, d := range def.Entries[0].Defs {
+			for _, s := range d.Senses {
+				senses = append(senses, s.Text)
+			}
+		}
+		if !reflect.DeepEqual(senses, tt.senses) {
+			t.Errorf("%s: Parse returned senses %q, want %q", tt.name, senses, tt.senses)
+		}
+		checkDeterministic(t, tt.name, []byte(tt.body))
+	}
+}
+
+// checkDeterministic checks that parsing body again returns the same definition and warnings.
+func checkDeterministic(t *testing.T, name string, body []byte) {
+	t.Helper()
+	def, warnings, err := dictapi.Parse(body)
+	for i := 0; i < 5; i++ {
+		again, againWarnings, againErr := dictapi.Parse(body)
+		a, _ := json.Marshal(def)
+		b, _ := json.Marshal(again)
+		if !bytes.Equal(a, b) || !reflect.DeepEqual(warnings, againWarnings) || fmt.Sprint(err) != fmt.Sprint(againErr) {
+			t.Errorf("%s: Parse returned different results for the same body", name)
+			return
+		}
+	}
+}
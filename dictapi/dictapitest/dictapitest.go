@@ -0,0 +1,187 @@
+// dictapitest provides a fake dictionaryapi.com for testing the dictapi client without network or
+// API key. It serves responses recorded in fixture files: <reference>/<word>.json holds the body of
+// the response to /<reference>/json/<word> and, if the status isn't 200 OK, <reference>/<word>.status
+// holds it. The fixtures that come with the package are:
+//
+//	collegiate/ephemeral.json    two entries, an adjective with a variant and a synonym discussion,
+//	                             and a noun with an alternate headword and labels
+//	collegiate/accept.json       a verb with every kind of sense, usage notes and usage paragraphs
+//	collegiate/ephemerl.json     spelling suggestions
+//	collegiate/qwzxv.json        no results
+//	collegiate/unavailable.json  503 Service Unavailable
+//	thesaurus/ephemeral.json     synonyms and antonyms
+//	thesaurus/ephemerl.json      spelling suggestions
+//
+// Point a client at the server:
+//
+//	srv := dictapitest.NewServer(dictapitest.Fixtures())
+//	defer srv.Close()
+//	c := &dictapi.Client{Endpoint: srv.URL, APIKey: dictapitest.Key}
+//
+// Code that calls the functions of dictapi is pointed at it with dictapi.Endpoint and
+// dictapi.APIKey instead, from tests that don't run in parallel.
+package dictapitest
+
+import (
+	"embed"
+	"fmt"
+	"io"
+	"io/fs"
+	"lexicon/dictapi"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Key is the API key accepted by the servers of this package.
+const Key = "dictapitest"
+
+//go:embed fixtures
+var fixtures embed.FS
+
+// Fixtures returns the fixtures that come with the package.
+func Fixtures() fs.FS {
+	sub, err := fs.Sub(fixtures, "fixtures")
+	if err != nil {
+		panic(err)
+	}
+	return sub
+}
+
+// NewServer starts a fake dictionaryapi.com that serves the fixtures in fsys, e.g. Fixtures() or
+// os.DirFS of a directory written by NewRecorder. The caller must close it.
+func NewServer(fsys fs.FS) *httptest.Server {
+	return httptest.NewServer(Handler(fsys))
+}
+
+// Handler serves the fixtures in fsys. Requests without Key get 403 Forbidden and words without
+// fixture get 404 Not Found, which the real service never returns, so that missing fixtures are
+// noticed.
+func Handler(fsys fs.FS) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reference, word, ok := parsePath(r)
+		if !ok {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		if r.URL.Query().Get("key") != Key {
+			http.Error(w, "Invalid API key. Not subscribed for this reference.", http.StatusForbidden)
+			return
+		}
+
+		name := path.Join(reference, word)
+		body, err := fs.ReadFile(fsys, name+".json")
+		if err != nil {
+			http.Error(w, fmt.Sprintf("no fixture for %s", name), http.StatusNotFound)
+			return
+		}
+		status := http.StatusOK
+		if b, err := fs.ReadFile(fsys, name+".status"); err == nil {
+			if status, err = strconv.Atoi(strings.TrimSpace(string(b))); err != nil {
+				http.Error(w, fmt.Sprintf("invalid status of %s: %s", name, err), http.StatusInternalServerError)
+				return
+			}
+		}
+		if status == http.StatusOK {
+			w.Header().Set("Content-Type", "application/json")
+		}
+		w.WriteHeader(status)
+		_, _ = w.Write(body)
+	})
+}
+
+// NewRecorder starts a server that forwards requests to the real dictionaryapi.com with apiKey
+// and writes the responses as fixtures to dir. Clients use Key like with NewServer, so apiKey
+// isn't written anywhere. Run the code under test against it once to record the fixtures, and
+// against NewServer(os.DirFS(dir)) afterwards. The caller must close it.
+func NewRecorder(dir, apiKey string) *httptest.Server {
+	return httptest.NewServer(Recorder(dir, dictapi.DefaultEndpoint, apiKey))
+}
+
+// Recorder forwards requests to the references at endpoint with apiKey and writes the responses
+// as fixtures to dir, replacing those that were already there.
+func Recorder(dir, endpoint, apiKey string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reference, word, ok := parsePath(r)
+		if !ok {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		if r.URL.Query().Get("key") != Key {
+			http.Error(w, "Invalid API key. Not subscribed for this reference.", http.StatusForbidden)
+			return
+		}
+
+		u := fmt.Sprintf("%s/%s/json/%s?key=%s", strings.TrimSuffix(endpoint, "/"), reference,
+			url.PathEscape(word), url.QueryEscape(apiKey))
+		req, err := http.NewRequestWithContext(r.Context(), http.MethodGet, u, nil)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		defer res.Body.Close()
+		body, err := io.ReadAll(res.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+
+		if err := writeFixture(filepath.Join(dir, reference, word), res.StatusCode, body); err != nil {
+			log.Printf("Unable to record %s/%s: %s", reference, word, err)
+		}
+		if ct := res.Header.Get("Content-Type"); ct != "" {
+			w.Header().Set("Content-Type", ct)
+		}
+		w.WriteHeader(res.StatusCode)
+		_, _ = w.Write(body)
+	})
+}
+
+// writeFixture writes the body of a response to name.json and its status, unless it's 200 OK, to
+// name.status.
+func writeFixture(name string, status int, body []byte) error {
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(name+".json", body, 0644); err != nil {
+		return err
+	}
+	if status == http.StatusOK {
+		err := os.Remove(name + ".status")
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	return os.WriteFile(name+".status", []byte(strconv.Itoa(status)+"\n"), 0644)
+}
+
+// parsePath returns the reference and the word of a /<reference>/json/<word> request.
+func parsePath(r *http.Request) (reference, word string, ok bool) {
+	if r.Method != http.MethodGet {
+		return "", "", false
+	}
+	parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"), "/", 3)
+	if len(parts) != 3 || parts[1] != "json" {
+		return "", "", false
+	}
+	reference, word = parts[0], parts[2]
+	// The word becomes a file name.
+	for _, s := range []string{reference, word} {
+		if s == "" || s == "." || s == ".." || strings.ContainsAny(s, `/\`) {
+			return "", "", false
+		}
+	}
+	return reference, word, true
+}
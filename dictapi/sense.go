@@ -0,0 +1,158 @@
+package dictapi
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Sseq is a sense sequence: groups of senses that belong together, e.g. 1 a and 1 b.
+// Ref: https://dictionaryapi.com/products/json#sec-2.sseq
+type Sseq [][]SenseItem
+
+// SenseItem is an element of a sense sequence, a ["type", value] pair. The types are sense, sen (a
+// truncated sense), bs (a binding substitute, a sense that applies to the ones after it) and pseq (a
+// parenthesized sense sequence). The value of other types is kept in Raw, and so is that of items
+// that can't be decoded, with the reason in Err, so that one malformed item doesn't fail the whole
+// response.
+type SenseItem struct {
+	Type  string
+	Sense *DSense     // sense, sen and bs.
+	Pseq  []SenseItem // pseq.
+	Raw   json.RawMessage
+	Err   error
+}
+
+func (s *SenseItem) UnmarshalJSON(b []byte) error {
+	typ, value, err := decodePair(b)
+	if err != nil {
+		*s = SenseItem{Raw: append(json.RawMessage(nil), b...), Err: err}
+		return nil
+	}
+	*s = SenseItem{Type: typ}
+	switch typ {
+	case "sense", "sen":
+		err = json.Unmarshal(value, &s.Sense)
+	case "bs":
+		var bs struct {
+			Sense *DSense `json:"sense"`
+		}
+		err = json.Unmarshal(value, &bs)
+		s.Sense = bs.Sense
+	case "pseq":
+		err = json.Unmarshal(value, &s.Pseq)
+	default:
+		s.Raw = value
+	}
+	if err != nil {
+		*s = SenseItem{Type: typ, Raw: value, Err: fmt.Errorf("invalid %s: %s", typ, err)}
+	}
+	return nil
+}
+
+// DSense is a sense: its number, labels and defining text.
+type DSense struct {
+	Sn      string    `json:"sn"`
+	Sls     []string  `json:"sls"`
+	Lbs     []string  `json:"lbs"`
+	Dt      Dt        `json:"dt"`
+	Sdsense *DSdsense `json:"sdsense"`
+}
+
+// DSdsense is a divided sense, e.g. the "specifically" part of a sense.
+type DSdsense struct {
+	Sd  string   `json:"sd"`
+	Sls []string `json:"sls"`
+	Dt  Dt       `json:"dt"`
+}
+
+// Dt is a defining text, the elements that make up a sense.
+// Ref: https://dictionaryapi.com/products/json#sec-2.dt
+type Dt []DtElement
+
+// DtElement is an element of a defining text, a ["type", value] pair. The types are text, vis
+// (verbal illustrations), uns (usage notes), snote (a supplemental note), ri (run-in entries), bnw
+// (a biographical name) and ca (called-also words). Usage notes, supplemental notes and run-ins hold
+// elements too: t, the text of a supplemental note, and riw, a run-in word. The value of other types
+// is kept in Raw, and so is that of elements that can't be decoded, with the reason in Err.
+type DtElement struct {
+	Type  string
+	Text  string   // text and t.
+	Vis   []DQuote // vis.
+	Uns   []Dt     // uns, one Dt per usage note.
+	Snote Dt       // snote.
+	Ri    Dt       // ri.
+	Riw   *DRiw    // riw.
+	Bnw   *DBnw    // bnw.
+	Ca    *DCa     // ca.
+	Raw   json.RawMessage
+	Err   error
+}
+
+func (e *DtElement) UnmarshalJSON(b []byte) error {
+	typ, value, err := decodePair(b)
+	if err != nil {
+		*e = DtElement{Raw: append(json.RawMessage(nil), b...), Err: err}
+		return nil
+	}
+	*e = DtElement{Type: typ}
+	switch typ {
+	case "text", "t":
+		err = json.Unmarshal(value, &e.Text)
+	case "vis":
+		err = json.Unmarshal(value, &e.Vis)
+	case "uns":
+		err = json.Unmarshal(value, &e.Uns)
+	case "snote":
+		err = json.Unmarshal(value, &e.Snote)
+	case "ri":
+		err = json.Unmarshal(value, &e.Ri)
+	case "riw":
+		err = json.Unmarshal(value, &e.Riw)
+	case "bnw":
+		err = json.Unmarshal(value, &e.Bnw)
+	case "ca":
+		err = json.Unmarshal(value, &e.Ca)
+	default:
+		e.Raw = value
+	}
+	if err != nil {
+		*e = DtElement{Type: typ, Raw: value, Err: fmt.Errorf("invalid %s: %s", typ, err)}
+	}
+	return nil
+}
+
+// DRiw is a run-in word.
+type DRiw struct {
+	Rie string `json:"rie"`
+	Prs []DPrs `json:"prs"`
+}
+
+// DBnw is a biographical name.
+type DBnw struct {
+	Pname   string `json:"pname"`
+	Sname   string `json:"sname"`
+	Altname string `json:"altname"`
+	Prs     []DPrs `json:"prs"`
+}
+
+// DCa lists the other names of a sense: its intro, usually "called also", and the words.
+type DCa struct {
+	Intro string `json:"intro"`
+	Cats  []struct {
+		Cat    string `json:"cat"`
+		Catref string `json:"catref"`
+		Pn     string `json:"pn"`
+		Prs    []DPrs `json:"prs"`
+		Psl    string `json:"psl"`
+	} `json:"cats"`
+}
+
+// decodePair decodes a ["type", value] array.
+func decodePair(b []byte) (string, json.RawMessage, error) {
+	var pair []json.RawMessage
+	var typ string
+	if json.Unmarshal(b, &pair) != nil || len(pair) != 2 || json.Unmarshal(pair[0], &typ) != nil {
+		return "", nil, fmt.Errorf("expected a [type, value] pair, got %s", b)
+	}
+	return typ, pair[1], nil
+}
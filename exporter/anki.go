@@ -0,0 +1,287 @@
+package exporter
+
+import (
+	"archive/zip"
+	"crypto/sha1"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"lexicon/types"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// ankiExporter writes an Anki package (.apkg) with a card per lexeme. The front of the card has the
+// word and its pronunciation and the back has the short definitions and verbal illustrations.
+//
+// A package is a zip file with an SQLite database in the legacy collection format (schema 11) and a
+// media manifest. The deck and note type have fixed IDs and notes have GUIDs derived from the word,
+// so importing a new export into Anki updates the existing cards instead of duplicating them.
+type ankiExporter struct{}
+
+const (
+	ankiDeckID  = 1718281828459
+	ankiModelID = 1618033988749
+)
+
+var ankiSchema = []string{
+	`CREATE TABLE col (id integer primary key, crt integer not null, mod integer not null,
+		scm integer not null, ver integer not null, dty integer not null, usn integer not null,
+		ls integer not null, conf text not null, models text not null, decks text not null,
+		dconf text not null, tags text not null)`,
+	`CREATE TABLE notes (id integer primary key, guid text not null, mid integer not null,
+		mod integer not null, usn integer not null, tags text not null, flds text not null,
+		sfld integer not null, csum integer not null, flags integer not null, data text not null)`,
+	`CREATE TABLE cards (id integer primary key, nid integer not null, did integer not null,
+		ord integer not null, mod integer not null, usn integer not null, type integer not null,
+		queue integer not null, due integer not null, ivl integer not null, factor integer not null,
+		reps integer not null, lapses integer not null, left integer not null, odue integer not null,
+		odid integer not null, flags integer not null, data text not null)`,
+	`CREATE TABLE revlog (id integer primary key, cid integer not null, usn integer not null,
+		ease integer not null, ivl integer not null, lastIvl integer not null, factor integer not null,
+		time integer not null, type integer not null)`,
+	`CREATE TABLE graves (usn integer not null, oid integer not null, type integer not null)`,
+	`CREATE INDEX ix_notes_usn on notes (usn)`,
+	`CREATE INDEX ix_cards_usn on cards (usn)`,
+	`CREATE INDEX ix_revlog_usn on revlog (usn)`,
+	`CREATE INDEX ix_cards_nid on cards (nid)`,
+	`CREATE INDEX ix_cards_sched on cards (did, queue, due)`,
+	`CREATE INDEX ix_revlog_cid on revlog (cid)`,
+	`CREATE INDEX ix_notes_csum on notes (csum)`,
+}
+
+const ankiCSS = `.card { font-family: Georgia, serif; font-size: 20px; text-align: center; color: black; background-color: white; }
+.pron { color: #666; font-size: 16px; }
+.back { text-align: left; }
+.vis { color: #555; font-style: italic; }`
+
+func (ankiExporter) Export(w io.Writer, lexemes []*types.Lexeme) error {
+	dir, err := os.MkdirTemp("", "lexicon-anki")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	collection := filepath.Join(dir, "collection.anki2")
+	if err := writeAnkiCollection(collection, lexemes); err != nil {
+		return err
+	}
+
+	zw := zip.NewWriter(w)
+	f, err := zw.Create("collection.anki2")
+	if err != nil {
+		return err
+	}
+	buf, err := os.ReadFile(collection)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(buf); err != nil {
+		return err
+	}
+
+	media, err := zw.Create("media")
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(media, "{}"); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+func writeAnkiCollection(path string, lexemes []*types.Lexeme) error {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, stmt := range ankiSchema {
+		if _, err := tx.Exec(stmt); err != nil {
+			return fmt.Errorf("unable to create Anki collection: %s", err)
+		}
+	}
+
+	now := time.Now()
+	millis := now.UnixNano() / 1e6
+	conf, models, decks, dconf, err := ankiCollectionConfig(now)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`INSERT INTO col VALUES(1, ?, ?, ?, 11, 0, 0, 0, ?, ?, ?, ?, '{}')`,
+		now.Unix(), millis, millis, conf, models, decks, dconf)
+	if err != nil {
+		return err
+	}
+
+	// Note and card IDs are timestamps in milliseconds and must be unique.
+	id := millis
+	for i, lex := range lexemes {
+		front, back := ankiFields(summarize(lex))
+		sum := sha1.Sum([]byte(lex.Name))
+		csum, _ := strconv.ParseInt(hex.EncodeToString(sum[:4]), 16, 64)
+		guid := hex.EncodeToString(sum[:8])
+		tags := ""
+		if len(lex.Tags) > 0 {
+			tags = " " + strings.Join(lex.Tags, " ") + " "
+		}
+
+		noteID := id + int64(2*i)
+		_, err := tx.Exec(`INSERT INTO notes VALUES(?, ?, ?, ?, -1, ?, ?, ?, ?, 0, '')`,
+			noteID, guid, ankiModelID, now.Unix(), tags, front+"\x1f"+back, lex.Name, csum)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`INSERT INTO cards VALUES(?, ?, ?, 0, ?, -1, 0, 0, ?, 0, 0, 0, 0, 0, 0, 0, 0, '')`,
+			noteID+1, noteID, ankiDeckID, now.Unix(), i+1)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// ankiFields returns the HTML of the front and back of a card.
+func ankiFields(s summary) (string, string) {
+	front := html.EscapeString(s.Name)
+	if len(s.Pronunciations) > 0 {
+		front += fmt.Sprintf(`<div class="pron">\%s\</div>`, html.EscapeString(strings.Join(s.Pronunciations, ", ")))
+	}
+
+	var back strings.Builder
+	back.WriteString(`<div class="back">`)
+	if len(s.ShortDefinitions) > 0 {
+		back.WriteString("<ul>")
+		for _, sd := range s.ShortDefinitions {
+			back.WriteString("<li>" + html.EscapeString(sd) + "</li>")
+		}
+		back.WriteString("</ul>")
+	}
+	for _, vi := range s.VerbalIllustrations {
+		back.WriteString(`<div class="vis">` + html.EscapeString(vi) + "</div>")
+	}
+	back.WriteString("</div>")
+	return front, back.String()
+}
+
+// ankiCollectionConfig returns the JSON documents stored in the col table: the collection
+// configuration, the note types, the decks and the deck options.
+func ankiCollectionConfig(now time.Time) (conf, models, decks, dconf string, err error) {
+	model := map[string]interface{}{
+		"id":        ankiModelID,
+		"name":      "Lexicon",
+		"type":      0,
+		"mod":       now.Unix(),
+		"usn":       -1,
+		"sortf":     0,
+		"did":       ankiDeckID,
+		"css":       ankiCSS,
+		"latexPre":  "\\documentclass[12pt]{article}\n\\special{papersize=3in,5in}\n\\usepackage[utf8]{inputenc}\n\\usepackage{amssymb,amsmath}\n\\pagestyle{empty}\n\\setlength{\\parindent}{0in}\n\\begin{document}\n",
+		"latexPost": "\\end{document}",
+		"tags":      []string{},
+		"vers":      []string{},
+		"req":       []interface{}{[]interface{}{0, "all", []int{0}}},
+		"flds": []map[string]interface{}{
+			{"name": "Front", "ord": 0, "sticky": false, "rtl": false, "font": "Arial", "size": 20, "media": []string{}},
+			{"name": "Back", "ord": 1, "sticky": false, "rtl": false, "font": "Arial", "size": 20, "media": []string{}},
+		},
+		"tmpls": []map[string]interface{}{{
+			"name":  "Card 1",
+			"ord":   0,
+			"qfmt":  "{{Front}}",
+			"afmt":  "{{FrontSide}}<hr id=answer>{{Back}}",
+			"did":   nil,
+			"bqfmt": "",
+			"bafmt": "",
+		}},
+	}
+
+	deck := func(id int64, name string) map[string]interface{} {
+		return map[string]interface{}{
+			"id":               id,
+			"name":             name,
+			"desc":             "",
+			"mod":              now.Unix(),
+			"usn":              -1,
+			"collapsed":        false,
+			"newToday":         []int{0, 0},
+			"revToday":         []int{0, 0},
+			"lrnToday":         []int{0, 0},
+			"timeToday":        []int{0, 0},
+			"dyn":              0,
+			"conf":             1,
+			"extendNew":        10,
+			"extendRev":        50,
+			"browserCollapsed": false,
+		}
+	}
+
+	options := map[string]interface{}{
+		"id":       1,
+		"name":     "Default",
+		"mod":      0,
+		"usn":      0,
+		"maxTaken": 60,
+		"autoplay": true,
+		"timer":    0,
+		"replayq":  true,
+		"dyn":      false,
+		"new": map[string]interface{}{
+			"delays": []int{1, 10}, "ints": []int{1, 4, 7}, "initialFactor": 2500,
+			"separate": true, "order": 1, "perDay": 20, "bury": true,
+		},
+		"rev": map[string]interface{}{
+			"perDay": 100, "ease4": 1.3, "fuzz": 0.05, "minSpace": 1, "ivlFct": 1,
+			"maxIvl": 36500, "bury": true,
+		},
+		"lapse": map[string]interface{}{
+			"delays": []int{10}, "mult": 0, "minInt": 1, "leechFails": 8, "leechAction": 0,
+		},
+	}
+
+	cfg := map[string]interface{}{
+		"nextPos":       1,
+		"estTimes":      true,
+		"activeDecks":   []int64{1},
+		"sortType":      "noteFld",
+		"timeLim":       0,
+		"sortBackwards": false,
+		"addToCur":      true,
+		"curDeck":       1,
+		"newSpread":     0,
+		"dueCounts":     true,
+		"curModel":      strconv.FormatInt(ankiModelID, 10),
+		"collapseTime":  1200,
+	}
+
+	docs := []interface{}{
+		cfg,
+		map[string]interface{}{strconv.FormatInt(ankiModelID, 10): model},
+		map[string]interface{}{"1": deck(1, "Default"), strconv.FormatInt(ankiDeckID, 10): deck(ankiDeckID, "Lexicon")},
+		map[string]interface{}{"1": options},
+	}
+	var res []string
+	for _, doc := range docs {
+		buf, err := json.Marshal(doc)
+		if err != nil {
+			return "", "", "", "", err
+		}
+		res = append(res, string(buf))
+	}
+	return res[0], res[1], res[2], res[3], nil
+}
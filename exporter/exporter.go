@@ -0,0 +1,125 @@
+// exporter writes the lexicon in formats other tools understand: flashcards for Anki, spreadsheets,
+// JSON documents and static pages.
+package exporter
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"lexicon/types"
+	"lexicon/util"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Exporter writes a list of lexemes to w.
+type Exporter interface {
+	Export(w io.Writer, lexemes []*types.Lexeme) error
+}
+
+// Supported formats.
+const (
+	Anki     = "anki"
+	CSV      = "csv"
+	JSONL    = "jsonl"
+	Markdown = "md"
+	HTML     = "html"
+)
+
+var exporters = map[string]Exporter{
+	Anki:     ankiExporter{},
+	CSV:      csvExporter{},
+	JSONL:    jsonlExporter{},
+	Markdown: markdownExporter{},
+	HTML:     htmlExporter{},
+}
+
+// Formats returns the names of the supported formats.
+func Formats() []string {
+	var formats []string
+	for f := range exporters {
+		formats = append(formats, f)
+	}
+	sort.Strings(formats)
+	return formats
+}
+
+// Export writes lexemes to w in the given format.
+func Export(format string, w io.Writer, lexemes []*types.Lexeme) error {
+	exp, ok := exporters[format]
+	if !ok {
+		return fmt.Errorf("unknown format %q, supported formats: %s", format, strings.Join(Formats(), ", "))
+	}
+	return exp.Export(w, lexemes)
+}
+
+// Filter selects the lexemes to export. Zero values match everything.
+type Filter struct {
+	From time.Time // Lexemes created on or after From.
+	To   time.Time // Lexemes created before To.
+	Tag  string    // Lexemes with this tag.
+}
+
+// Apply returns the lexemes that match the filter sorted by name.
+func (f Filter) Apply(lexemes []*types.Lexeme) []*types.Lexeme {
+	var res []*types.Lexeme
+	for _, lex := range lexemes {
+		if !f.From.IsZero() && (lex.CreatedAt == nil || lex.CreatedAt.Before(f.From)) {
+			continue
+		}
+		if !f.To.IsZero() && (lex.CreatedAt == nil || !lex.CreatedAt.Before(f.To)) {
+			continue
+		}
+		if f.Tag != "" && !util.Contains(lex.Tags, f.Tag) {
+			continue
+		}
+		res = append(res, lex)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+	return res
+}
+
+// summary is the subset of a lexeme that most formats show.
+type summary struct {
+	Name                string
+	Pronunciations      []string
+	ShortDefinitions    []string
+	VerbalIllustrations []string
+	Tags                []string
+	Notes               string
+	CreatedAt           string
+}
+
+// summarize parses the definition of a lexeme. Lexemes with invalid definitions are still exported
+// with their name.
+func summarize(lex *types.Lexeme) summary {
+	s := summary{Name: lex.Name, Tags: lex.Tags, Notes: lex.Notes}
+	if lex.CreatedAt != nil {
+		s.CreatedAt = lex.CreatedAt.Format("2006-01-02")
+	}
+
+	var def types.Definition
+	if err := json.Unmarshal([]byte(lex.Definition), &def); err != nil {
+		return s
+	}
+	for _, e := range def.Entries {
+		for _, p := range e.Headword.Pronunciations {
+			if p.Text != "" && !util.Contains(s.Pronunciations, p.Text) {
+				s.Pronunciations = append(s.Pronunciations, p.Text)
+			}
+		}
+		for _, sd := range e.ShortDefinitions {
+			if len(e.GrammaticalFunction) > 0 {
+				sd = fmt.Sprintf("(%s) %s", e.GrammaticalFunction, sd)
+			}
+			s.ShortDefinitions = append(s.ShortDefinitions, sd)
+		}
+		for _, d := range e.Defs {
+			for _, sense := range d.Senses {
+				s.VerbalIllustrations = append(s.VerbalIllustrations, sense.VerbalIllustrations...)
+			}
+		}
+	}
+	return s
+}
@@ -0,0 +1,77 @@
+package exporter
+
+import (
+	"html/template"
+	"io"
+	"lexicon/types"
+	"strings"
+	"unicode"
+)
+
+// htmlExporter writes a static, self-contained HTML glossary with an index by initial letter.
+type htmlExporter struct{}
+
+type htmlLetter struct {
+	Letter  string
+	Entries []summary
+}
+
+func (htmlExporter) Export(w io.Writer, lexemes []*types.Lexeme) error {
+	var letters []htmlLetter
+	for _, lex := range lexemes {
+		letter := initial(lex.Name)
+		if len(letters) == 0 || letters[len(letters)-1].Letter != letter {
+			letters = append(letters, htmlLetter{Letter: letter})
+		}
+		last := &letters[len(letters)-1]
+		last.Entries = append(last.Entries, summarize(lex))
+	}
+	return glossaryTemplate.Execute(w, letters)
+}
+
+// initial returns the upper case first letter of name, or "#" if it doesn't start with a letter.
+func initial(name string) string {
+	for _, r := range name {
+		if unicode.IsLetter(r) {
+			return strings.ToUpper(string(r))
+		}
+		break
+	}
+	return "#"
+}
+
+var glossaryTemplate = template.Must(template.New("glossary").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Lexicon</title>
+<style>
+body { font-family: Georgia, serif; max-width: 48em; margin: 2em auto; padding: 0 1em; color: #222; }
+nav { position: sticky; top: 0; background: #fff; padding: .5em 0; border-bottom: 1px solid #ddd; }
+nav a { margin-right: .5em; text-decoration: none; }
+h2 { border-bottom: 1px solid #ddd; }
+.pron { color: #666; }
+.meta { color: #888; font-size: .85em; }
+blockquote { color: #555; font-style: italic; }
+</style>
+</head>
+<body>
+<h1>Lexicon</h1>
+<nav>{{range .}}<a href="#letter-{{.Letter}}">{{.Letter}}</a>{{end}}</nav>
+{{range .}}
+<section id="letter-{{.Letter}}">
+<h2>{{.Letter}}</h2>
+{{range .Entries}}
+<article id="{{.Name}}">
+<h3>{{.Name}}{{if .Pronunciations}} <span class="pron">\{{range $i, $p := .Pronunciations}}{{if $i}}, {{end}}{{$p}}{{end}}\</span>{{end}}</h3>
+{{if .ShortDefinitions}}<ul>{{range .ShortDefinitions}}<li>{{.}}</li>{{end}}</ul>{{end}}
+{{range .VerbalIllustrations}}<blockquote>{{.}}</blockquote>{{end}}
+{{if .Notes}}<p>{{.Notes}}</p>{{end}}
+<p class="meta">{{if .CreatedAt}}Added on {{.CreatedAt}}{{end}}{{if .Tags}} · {{range $i, $t := .Tags}}{{if $i}}, {{end}}{{$t}}{{end}}{{end}}</p>
+</article>
+{{end}}
+</section>
+{{end}}
+</body>
+</html>
+`))
@@ -0,0 +1,113 @@
+package exporter
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"lexicon/types"
+	"strings"
+	"time"
+)
+
+// csvExporter writes a CSV file with a header row. The columns are compatible with the CSV importer.
+type csvExporter struct{}
+
+func (csvExporter) Export(w io.Writer, lexemes []*types.Lexeme) error {
+	cw := csv.NewWriter(w)
+	header := []string{"word", "pronunciation", "definitions", "tags", "notes", "source", "created_at", "updated_at"}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, lex := range lexemes {
+		s := summarize(lex)
+		row := []string{
+			lex.Name,
+			strings.Join(s.Pronunciations, ", "),
+			strings.Join(s.ShortDefinitions, " | "),
+			strings.Join(lex.Tags, ";"),
+			lex.Notes,
+			lex.Source,
+			formatTimestamp(lex.CreatedAt),
+			formatTimestamp(lex.UpdatedAt),
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func formatTimestamp(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// jsonlExporter writes one lexeme per line as a JSON object. The output can be imported back.
+type jsonlExporter struct{}
+
+func (jsonlExporter) Export(w io.Writer, lexemes []*types.Lexeme) error {
+	enc := json.NewEncoder(w)
+	for _, lex := range lexemes {
+		if err := enc.Encode(lex); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// markdownExporter writes a Markdown document with a section per lexeme.
+type markdownExporter struct{}
+
+func (markdownExporter) Export(w io.Writer, lexemes []*types.Lexeme) error {
+	if _, err := fmt.Fprintf(w, "# Lexicon\n"); err != nil {
+		return err
+	}
+	for _, lex := range lexemes {
+		if err := WriteMarkdown(w, lex); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteMarkdown writes the Markdown section of a lexeme, a level 2 heading followed by its
+// pronunciations, definitions, examples and notes.
+func WriteMarkdown(w io.Writer, lex *types.Lexeme) error {
+	var out strings.Builder
+	s := summarize(lex)
+	_, _ = fmt.Fprintf(&out, "\n## %s\n", s.Name)
+	if len(s.Pronunciations) > 0 {
+		_, _ = fmt.Fprintf(&out, "\n\\\\%s\\\\\n", strings.Join(s.Pronunciations, ", "))
+	}
+	if len(s.ShortDefinitions) > 0 {
+		_, _ = fmt.Fprintf(&out, "\n")
+		for _, sd := range s.ShortDefinitions {
+			_, _ = fmt.Fprintf(&out, "- %s\n", sd)
+		}
+	}
+	if len(s.VerbalIllustrations) > 0 {
+		_, _ = fmt.Fprintf(&out, "\n")
+		for _, vi := range s.VerbalIllustrations {
+			_, _ = fmt.Fprintf(&out, "> %s\n>\n", vi)
+		}
+	}
+	if len(s.Notes) > 0 {
+		_, _ = fmt.Fprintf(&out, "\n%s\n", s.Notes)
+	}
+	var meta []string
+	if s.CreatedAt != "" {
+		meta = append(meta, "Added on "+s.CreatedAt)
+	}
+	if len(s.Tags) > 0 {
+		meta = append(meta, "Tags: "+strings.Join(s.Tags, ", "))
+	}
+	if len(meta) > 0 {
+		_, _ = fmt.Fprintf(&out, "\n*%s*\n", strings.Join(meta, " · "))
+	}
+	_, err := io.WriteString(w, out.String())
+	return err
+}
@@ -3,6 +3,11 @@ module lexicon
 go 1.16
 
 require (
-	github.com/fatih/color v1.16.0 // indirect
+	github.com/BurntSushi/toml v1.3.2
+	github.com/fatih/color v1.16.0
+	github.com/mattn/go-isatty v0.0.20
 	github.com/mattn/go-sqlite3 v1.14.22
+	github.com/peterh/liner v1.2.2
+	golang.org/x/term v0.14.0
+	gopkg.in/yaml.v3 v3.0.1
 )
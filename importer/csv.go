@@ -0,0 +1,68 @@
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"os"
+)
+
+// csvImporter reads CSV files with a header row. Columns are mapped by name (word, timestamp, tags,
+// notes and source, plus a few common aliases) and unknown columns are ignored. Files whose first
+// row has no word column are read as "word,timestamp" lines, like the text format, which was the
+// only one supported by define-batch at first.
+type csvImporter struct{}
+
+func (csvImporter) Read(path string) ([]Record, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+
+	header, err := r.Read()
+	if err != nil {
+		if err == io.EOF {
+			return nil, errors.New("empty CSV file")
+		}
+		return nil, err
+	}
+	if !hasWordColumn(header) {
+		return textImporter{}.Read(path)
+	}
+
+	var records []Record
+	for {
+		row, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		line, _ := r.FieldPos(0)
+		fields := make(map[string]string)
+		for i, v := range row {
+			if i < len(header) {
+				fields[header[i]] = v
+			}
+		}
+		records = append(records, newRecord(line, fields))
+	}
+	return records, nil
+}
+
+// hasWordColumn reports whether a header row names the column of the words.
+func hasWordColumn(header []string) bool {
+	for _, h := range header {
+		if fieldOf(h) == "word" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,66 @@
+package importer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCSV(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string // word@line, or !line for records with errors.
+	}{
+		{
+			name:    "header",
+			content: "Word,Created At,tags\napple,2024-01-02,fruit\npear,,\n",
+			want:    []string{"apple@2", "pear@3"},
+		},
+		{
+			name:    "header with alias",
+			content: "source,term\nA book,Ephemeral\n",
+			want:    []string{"ephemeral@2"},
+		},
+		{
+			name:    "legacy lines",
+			content: "apple,2006-01-02 15:04:05\npear\n\nfig,2006-01-02 15:04:05\n",
+			want:    []string{"apple@1", "pear@2", "fig@4"},
+		},
+		{
+			name:    "header without word column",
+			content: "date,tags\n2024-01-02,fruit\n",
+			want:    []string{"!1", "!2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "words.csv")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			records, err := Read("", path)
+			if err != nil {
+				t.Fatalf("Read: %s", err)
+			}
+			var got []string
+			for _, rec := range records {
+				if rec.Err != nil {
+					got = append(got, fmt.Sprintf("!%d", rec.Line))
+				} else {
+					got = append(got, fmt.Sprintf("%s@%d", rec.Word, rec.Line))
+				}
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("got %v, want %v", got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,220 @@
+// importer reads words from files exported by other tools so they can be defined and saved in the
+// lexicon while keeping their original dates.
+package importer
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Record is a word read from an import file.
+type Record struct {
+	Line      int        `json:"line"`                // Position of the record in the file, starting at 1.
+	Word      string     `json:"word"`                // The word to define.
+	Timestamp *time.Time `json:"timestamp,omitempty"` // When the word was originally saved.
+	Tags      []string   `json:"tags,omitempty"`
+	Notes     string     `json:"notes,omitempty"`
+	Source    string     `json:"source,omitempty"` // Where the word was found, e.g. a book title.
+	Err       error      `json:"-"`                // Set when the record could not be parsed.
+}
+
+// Importer reads the records of a file.
+type Importer interface {
+	Read(path string) ([]Record, error)
+}
+
+// Supported formats.
+const (
+	Text           = "text"
+	CSV            = "csv"
+	JSON           = "json"
+	JSONL          = "jsonl"
+	Kindle         = "kindle"
+	MerriamWebster = "mw"
+)
+
+var importers = map[string]Importer{
+	Text:           textImporter{},
+	CSV:            csvImporter{},
+	JSON:           jsonImporter{},
+	JSONL:          jsonlImporter{},
+	Kindle:         kindleImporter{},
+	MerriamWebster: mwImporter{},
+}
+
+// Formats returns the names of the supported formats.
+func Formats() []string {
+	var formats []string
+	for f := range importers {
+		formats = append(formats, f)
+	}
+	sort.Strings(formats)
+	return formats
+}
+
+// Detect guesses the format of a file from its name. Files with unknown extensions are assumed to
+// be in the plain text format.
+func Detect(path string) string {
+	if strings.EqualFold(filepath.Base(path), "vocab.db") {
+		return Kindle
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return CSV
+	case ".json":
+		return JSON
+	case ".jsonl", ".ndjson":
+		return JSONL
+	case ".db", ".sqlite":
+		return Kindle
+	}
+	return Text
+}
+
+// Read reads the records of path in the given format. If format is empty it's detected from the
+// file name.
+func Read(format, path string) ([]Record, error) {
+	if format == "" {
+		format = Detect(path)
+	}
+	imp, ok := importers[format]
+	if !ok {
+		return nil, fmt.Errorf("unknown format %q, supported formats: %s", format, strings.Join(Formats(), ", "))
+	}
+	return imp.Read(path)
+}
+
+// fieldAliases lists the names accepted in CSV headers and JSON objects for every record field, in
+// order of priority: when a row has several names of one field, the first one with a value wins.
+var fieldAliases = []struct {
+	field   string
+	aliases []string
+}{
+	{"word", []string{"word", "name", "term", "headword"}},
+	{"timestamp", []string{"timestamp", "date", "created", "createdat", "added", "saved", "savedat"}},
+	{"tags", []string{"tags", "tag"}},
+	{"notes", []string{"notes", "note"}},
+	{"source", []string{"source"}},
+}
+
+// fieldKey returns the name of a field as listed in fieldAliases.
+func fieldKey(name string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(name), "_", ""))
+}
+
+// fieldOf returns the record field of a field name, or "" if it's unknown.
+func fieldOf(name string) string {
+	key := fieldKey(name)
+	for _, f := range fieldAliases {
+		for _, alias := range f.aliases {
+			if alias == key {
+				return f.field
+			}
+		}
+	}
+	return ""
+}
+
+// newRecord builds a record from a set of named fields as found in a CSV row or a JSON object. The
+// timestamp is that of the first name of the field with a value that parses, so that a bad value
+// only makes the record fail if no other name of the field has a good one.
+func newRecord(line int, fields map[string]string) Record {
+	// Names that are the same once normalized, e.g. "Word" and "word", are taken in sorted order.
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	byKey := make(map[string]string)
+	for _, name := range names {
+		if key := fieldKey(name); byKey[key] == "" {
+			byKey[key] = strings.TrimSpace(fields[name])
+		}
+	}
+	// values returns the values of a record field that aren't empty, in order of priority.
+	values := func(field string) []string {
+		var res []string
+		for _, f := range fieldAliases {
+			if f.field != field {
+				continue
+			}
+			for _, alias := range f.aliases {
+				if v := byKey[alias]; v != "" {
+					res = append(res, v)
+				}
+			}
+		}
+		return res
+	}
+	first := func(field string) string {
+		if vs := values(field); len(vs) > 0 {
+			return vs[0]
+		}
+		return ""
+	}
+
+	rec := Record{
+		Line:   line,
+		Word:   strings.ToLower(first("word")),
+		Tags:   splitTags(first("tags")),
+		Notes:  first("notes"),
+		Source: first("source"),
+	}
+	for _, v := range values("timestamp") {
+		t, err := parseTimestamp(v)
+		if err == nil {
+			rec.Timestamp, rec.Err = &t, nil
+			break
+		}
+		if rec.Err == nil {
+			rec.Err = err
+		}
+	}
+	if rec.Word == "" && rec.Err == nil {
+		rec.Err = fmt.Errorf("missing word")
+	}
+	return rec
+}
+
+var timestampLayouts = []string{
+	"2006-01-02 15:04:05",
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+	"Jan 2, 2006",
+	"January 2, 2006",
+}
+
+// parseTimestamp parses dates in the most common layouts as well as Unix timestamps in seconds or
+// milliseconds.
+func parseTimestamp(s string) (time.Time, error) {
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		// Timestamps after 1973 in milliseconds have more than 11 digits.
+		if n > 99999999999 {
+			return time.Unix(0, n*int64(time.Millisecond)), nil
+		}
+		return time.Unix(n, 0), nil
+	}
+	for _, layout := range timestampLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unable to parse timestamp %q", s)
+}
+
+// splitTags splits a list of tags separated by commas, semicolons or pipes.
+func splitTags(s string) []string {
+	var tags []string
+	fields := strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ';' || r == '|' })
+	for _, t := range fields {
+		if t = strings.TrimSpace(t); len(t) > 0 {
+			tags = append(tags, t)
+		}
+	}
+	return tags
+}
@@ -0,0 +1,90 @@
+package importer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// describe returns the fields of records that tests check: word@line followed by the timestamp in
+// UTC, tags, notes and source that are set, or !line for records with errors.
+func describe(records []Record) []string {
+	var res []string
+	for _, rec := range records {
+		if rec.Err != nil {
+			res = append(res, fmt.Sprintf("!%d", rec.Line))
+			continue
+		}
+		s := fmt.Sprintf("%s@%d", rec.Word, rec.Line)
+		if rec.Timestamp != nil {
+			s += " " + rec.Timestamp.UTC().Format("2006-01-02 15:04:05")
+		}
+		if len(rec.Tags) > 0 {
+			s += " tags=" + strings.Join(rec.Tags, ",")
+		}
+		if rec.Notes != "" {
+			s += fmt.Sprintf(" notes=%q", rec.Notes)
+		}
+		if rec.Source != "" {
+			s += fmt.Sprintf(" source=%q", rec.Source)
+		}
+		res = append(res, s)
+	}
+	return res
+}
+
+// readFile writes content to a file called name and reads it in format.
+func readFile(t *testing.T, format, name, content string) ([]Record, error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return Read(format, path)
+}
+
+func TestNewRecord(t *testing.T) {
+	tests := []struct {
+		fields map[string]string
+		want   string
+	}{
+		{map[string]string{"word": " Apple ", "tags": "a; b|c", "notes": "n", "source": "s"}, `apple@1 tags=a,b,c notes="n" source="s"`},
+		{map[string]string{"name": "pear", "word": "apple"}, "apple@1"},
+		{map[string]string{"headword": "pear", "term": "apple"}, "apple@1"},
+		{map[string]string{"name": "apple", "word": ""}, "apple@1"},
+		{map[string]string{"Word": "apple", "word": "pear"}, "apple@1"},
+		{map[string]string{"word": "apple", "date": "2024-02-02", "timestamp": "2024-01-01"}, "apple@1 2024-01-01 00:00:00"},
+		{map[string]string{"word": "apple", "date": "yesterday", "created_at": "2024-01-01"}, "apple@1 2024-01-01 00:00:00"},
+		{map[string]string{"word": "apple", "timestamp": "2024-01-01", "saved": "yesterday"}, "apple@1 2024-01-01 00:00:00"},
+		{map[string]string{"word": "apple", "date": "yesterday", "added": "tomorrow"}, "!1"},
+		{map[string]string{"word": "apple", "date": "1700000000"}, "apple@1 2023-11-14 22:13:20"},
+		{map[string]string{"word": "apple", "date": "1700000000123"}, "apple@1 2023-11-14 22:13:20"},
+		{map[string]string{"word": "apple", "date": "Jan 2, 2024"}, "apple@1 2024-01-02 00:00:00"},
+		{map[string]string{"date": "2024-01-01"}, "!1"},
+		{map[string]string{"word": "  "}, "!1"},
+	}
+	for _, tt := range tests {
+		// Maps are iterated in random order, the result must not depend on it.
+		for i := 0; i < 20; i++ {
+			got := describe([]Record{newRecord(1, tt.fields)})
+			if !reflect.DeepEqual(got, []string{tt.want}) {
+				t.Errorf("newRecord(%v) = %v, want %v", tt.fields, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestText(t *testing.T) {
+	records, err := readFile(t, "", "words.txt", "Apple,2024-01-02\n\n pear \nfig,someday\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"apple@1 2024-01-02 00:00:00", "pear@3", "!4"}
+	if got := describe(records); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
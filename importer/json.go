@@ -0,0 +1,83 @@
+package importer
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// jsonImporter reads a JSON array of objects with the same fields as the CSV format. Tags can be
+// either a string or an array of strings and timestamps either a string or a Unix timestamp.
+type jsonImporter struct{}
+
+func (jsonImporter) Read(path string) ([]Record, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var objects []map[string]interface{}
+	if err := json.Unmarshal(buf, &objects); err != nil {
+		return nil, err
+	}
+
+	var records []Record
+	for i, obj := range objects {
+		records = append(records, newRecord(i+1, stringFields(obj)))
+	}
+	return records, nil
+}
+
+// jsonlImporter reads files with one JSON object per line.
+type jsonlImporter struct{}
+
+func (jsonlImporter) Read(path string) ([]Record, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var records []Record
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if len(text) == 0 {
+			continue
+		}
+
+		var obj map[string]interface{}
+		if err := json.Unmarshal([]byte(text), &obj); err != nil {
+			records = append(records, Record{Line: line, Err: fmt.Errorf("invalid JSON: %s", err)})
+			continue
+		}
+		records = append(records, newRecord(line, stringFields(obj)))
+	}
+	return records, scanner.Err()
+}
+
+// stringFields converts the values of a JSON object to strings.
+func stringFields(obj map[string]interface{}) map[string]string {
+	fields := make(map[string]string)
+	for k, v := range obj {
+		switch x := v.(type) {
+		case string:
+			fields[k] = x
+		case float64:
+			fields[k] = fmt.Sprintf("%.0f", x)
+		case []interface{}:
+			var values []string
+			for _, e := range x {
+				values = append(values, fmt.Sprint(e))
+			}
+			fields[k] = strings.Join(values, ",")
+		case nil:
+		default:
+			fields[k] = fmt.Sprint(x)
+		}
+	}
+	return fields
+}
@@ -0,0 +1,72 @@
+package importer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+		err     bool
+	}{
+		{
+			name: "objects",
+			content: `[
+				{"word": "Apple", "tags": ["fruit", "red"], "created_at": 1700000000},
+				{"name": "pear", "notes": "a note", "source": "a book", "extra": {"x": 1}},
+				{"term": "fig", "date": "2024-01-02T03:04:05Z", "tag": "fruit"},
+				{"date": "2024-01-02"},
+				{"word": "plum", "date": "someday"}
+			]`,
+			want: []string{
+				"apple@1 2023-11-14 22:13:20 tags=fruit,red",
+				`pear@2 notes="a note" source="a book"`,
+				"fig@3 2024-01-02 03:04:05 tags=fruit",
+				"!4",
+				"!5",
+			},
+		},
+		{name: "empty", content: `[]`},
+		{name: "object", content: `{"word": "apple"}`, err: true},
+		{name: "invalid", content: `[{"word": "apple"`, err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, err := readFile(t, "", "words.json", tt.content)
+			if tt.err {
+				if err == nil {
+					t.Errorf("Read returned %q, want an error", describe(records))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Read: %s", err)
+			}
+			if got := describe(records); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJSONL(t *testing.T) {
+	content := `{"word": "apple", "timestamp": "2024-01-02 03:04:05"}
+
+{"word": "pear"
+{"name": "Fig", "tags": "a,b"}
+{"notes": "no word"}
+`
+	for _, name := range []string{"words.jsonl", "words.ndjson"} {
+		records, err := readFile(t, "", name, content)
+		if err != nil {
+			t.Fatalf("Read(%s): %s", name, err)
+		}
+		want := []string{"apple@1 2024-01-02 03:04:05", "!3", "fig@4 tags=a,b", "!5"}
+		if got := describe(records); !reflect.DeepEqual(got, want) {
+			t.Errorf("Read(%s) = %q, want %q", name, got, want)
+		}
+	}
+}
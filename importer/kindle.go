@@ -0,0 +1,73 @@
+package importer
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// kindleImporter reads the vocab.db SQLite database kept by Kindle e-readers with the words looked
+// up while reading. Every lookup of a word is merged into a single record whose timestamp is the
+// first lookup, and whose notes contain the sentences the word was found in along with the book
+// titles.
+type kindleImporter struct{}
+
+func (kindleImporter) Read(path string) ([]Record, error) {
+	db, err := sql.Open("sqlite3", fmt.Sprintf("file:%s?mode=ro", url.PathEscape(path)))
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	rows, err := db.Query(`
+		SELECT w.stem, COALESCE(l.usage, ''), COALESCE(b.title, ''), l.timestamp
+		FROM LOOKUPS l
+		JOIN WORDS w ON l.word_key = w.id
+		LEFT JOIN BOOK_INFO b ON l.book_key = b.id
+		ORDER BY l.timestamp`)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read Kindle vocabulary: %s", err)
+	}
+	defer rows.Close()
+
+	var records []Record
+	index := make(map[string]int)
+	for rows.Next() {
+		var stem, usage, title string
+		var timestamp int64
+		if err := rows.Scan(&stem, &usage, &title, &timestamp); err != nil {
+			return nil, err
+		}
+
+		word := strings.ToLower(strings.TrimSpace(stem))
+		i, ok := index[word]
+		if !ok {
+			t := time.Unix(0, timestamp*int64(time.Millisecond))
+			i = len(records)
+			index[word] = i
+			records = append(records, Record{
+				Line:      i + 1,
+				Word:      word,
+				Timestamp: &t,
+				Tags:      []string{"kindle"},
+				Source:    title,
+			})
+		}
+
+		if usage = strings.TrimSpace(usage); usage != "" {
+			note := fmt.Sprintf("%q", usage)
+			if title != "" {
+				note += " — " + title
+			}
+			if records[i].Notes != "" {
+				records[i].Notes += "\n"
+			}
+			records[i].Notes += note
+		}
+	}
+	return records, rows.Err()
+}
@@ -0,0 +1,53 @@
+package importer
+
+import (
+	"database/sql"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestKindle(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vocab.db")
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, stmt := range []string{
+		`CREATE TABLE WORDS (id TEXT PRIMARY KEY, word TEXT, stem TEXT)`,
+		`CREATE TABLE BOOK_INFO (id TEXT PRIMARY KEY, title TEXT)`,
+		`CREATE TABLE LOOKUPS (id TEXT PRIMARY KEY, word_key TEXT, book_key TEXT, usage TEXT, timestamp INTEGER)`,
+		`INSERT INTO WORDS VALUES ('en:ephemeral', 'ephemeral', 'Ephemeral'), ('en:accept', 'accepted', 'accept')`,
+		`INSERT INTO BOOK_INFO VALUES ('b1', 'A Book')`,
+		// 2023-11-14 22:13:20.123 UTC and later.
+		`INSERT INTO LOOKUPS VALUES
+			('l2', 'en:ephemeral', NULL, '  Fame is ephemeral. ', 1700000100000),
+			('l1', 'en:ephemeral', 'b1', 'An ephemeral joy.', 1700000000123),
+			('l3', 'en:accept', 'b1', '', 1700000200000)`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if format := Detect(path); format != Kindle {
+		t.Errorf("Detect(%s) = %s, want %s", path, format, Kindle)
+	}
+	records, err := Read("", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		`ephemeral@1 2023-11-14 22:13:20 tags=kindle notes="\"An ephemeral joy.\" — A Book\n\"Fame is ephemeral.\"" source="A Book"`,
+		`accept@2 2023-11-14 22:16:40 tags=kindle source="A Book"`,
+	}
+	if got := describe(records); !reflect.DeepEqual(got, want) {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+	if ms := records[0].Timestamp.UnixNano() / 1e6; ms != 1700000000123 {
+		t.Errorf("timestamp of the first lookup is %d ms, want 1700000000123", ms)
+	}
+}
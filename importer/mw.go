@@ -0,0 +1,72 @@
+package importer
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+)
+
+// mwImporter reads the words saved on Merriam-Webster. It accepts the JSON returned by the word
+// list API, either an array of items or an object with the items under "data.items" or "items",
+// where each item has a word and the date it was saved. It also accepts a plain list with one word
+// per line, optionally followed by a tab and the date, as copied from the saved words page.
+type mwImporter struct{}
+
+func (mwImporter) Read(path string) ([]Record, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []Record
+	trimmed := strings.TrimSpace(string(buf))
+	if strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+		items, err := mwItems([]byte(trimmed))
+		if err != nil {
+			return nil, err
+		}
+		for i, item := range items {
+			records = append(records, newRecord(i+1, stringFields(item)))
+		}
+	} else {
+		for i, line := range strings.Split(string(buf), "\n") {
+			line = strings.TrimSpace(line)
+			if len(line) == 0 {
+				continue
+			}
+			tokens := strings.SplitN(line, "\t", 2)
+			fields := map[string]string{"word": tokens[0]}
+			if len(tokens) == 2 {
+				fields["timestamp"] = tokens[1]
+			}
+			records = append(records, newRecord(i+1, fields))
+		}
+	}
+
+	for i := range records {
+		records[i].Tags = append(records[i].Tags, "merriam-webster")
+	}
+	return records, nil
+}
+
+func mwItems(buf []byte) ([]map[string]interface{}, error) {
+	var items []map[string]interface{}
+	if buf[0] == '[' {
+		err := json.Unmarshal(buf, &items)
+		return items, err
+	}
+
+	var doc struct {
+		Items []map[string]interface{} `json:"items"`
+		Data  struct {
+			Items []map[string]interface{} `json:"items"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(buf, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Data.Items) > 0 {
+		return doc.Data.Items, nil
+	}
+	return doc.Items, nil
+}
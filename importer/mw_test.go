@@ -0,0 +1,54 @@
+package importer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMerriamWebster(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+		err     bool
+	}{
+		{
+			name:    "array",
+			content: `[{"word": "Apple", "date": "2024-01-02"}, {"word": "pear"}]`,
+			want:    []string{"apple@1 2024-01-02 00:00:00 tags=merriam-webster", "pear@2 tags=merriam-webster"},
+		},
+		{
+			name:    "data items",
+			content: `{"data": {"items": [{"word": "apple", "saved_at": 1700000000}]}}`,
+			want:    []string{"apple@1 2023-11-14 22:13:20 tags=merriam-webster"},
+		},
+		{
+			name:    "items",
+			content: `{"items": [{"headword": "apple"}, {"date": "2024-01-02"}]}`,
+			want:    []string{"apple@1 tags=merriam-webster", "!2"},
+		},
+		{
+			name:    "list",
+			content: "Apple\tJan 2, 2024\n\npear\nfig\tsomeday\n",
+			want:    []string{"apple@1 2024-01-02 00:00:00 tags=merriam-webster", "pear@3 tags=merriam-webster", "!4"},
+		},
+		{name: "invalid JSON", content: `{"items": [`, err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, err := readFile(t, MerriamWebster, "saved.txt", tt.content)
+			if tt.err {
+				if err == nil {
+					t.Errorf("Read returned %q, want an error", describe(records))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Read: %s", err)
+			}
+			if got := describe(records); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package importer
+
+import (
+	"os"
+	"strings"
+)
+
+// textImporter reads files with one word per line, optionally followed by a comma and a timestamp,
+// e.g. "word,2006-01-02 15:04:05".
+type textImporter struct{}
+
+func (textImporter) Read(path string) ([]Record, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []Record
+	for i, line := range strings.Split(string(buf), "\n") {
+		line = strings.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+
+		tokens := strings.SplitN(line, ",", 2)
+		fields := map[string]string{"word": tokens[0]}
+		if len(tokens) == 2 {
+			fields["timestamp"] = tokens[1]
+		}
+		records = append(records, newRecord(i+1, fields))
+	}
+	return records, nil
+}
@@ -3,45 +3,51 @@ package lexapi
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
-	"lexicon/dictapi"
 	"lexicon/types"
 	"lexicon/util"
 	"log"
 	"net/http"
 	"net/url"
-	"os"
 	"strings"
 	"time"
 )
 
-const baseURL = "https://rafaelrendon.io"
+// DefaultURL is the address of the lexicon API.
+const DefaultURL = "https://rafaelrendon.io"
 
 var client *http.Client
 
 type APIDictionary struct {
-	httpc  *http.Client
-	apiKey string
+	httpc   *http.Client
+	baseURL string
+	apiKey  string
 }
 
 // NewDictionary return a new client ready to use.
-func NewDictionary() (*APIDictionary, error) {
-	apiKey := os.Getenv("API_KEY")
+func NewDictionary(apiKey string) (*APIDictionary, error) {
+	return NewDictionaryAt(DefaultURL, apiKey)
+}
+
+// NewDictionaryAt returns a new client of the lexicon API served at baseURL, e.g. a test server.
+func NewDictionaryAt(baseURL, apiKey string) (*APIDictionary, error) {
 	if len(apiKey) == 0 {
 		return nil, errors.New("API_KEY is missing")
 	}
 	return &APIDictionary{
-		httpc:  &http.Client{Timeout: time.Second * 3},
-		apiKey: apiKey,
+		httpc:   &http.Client{Timeout: time.Second * 3},
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		apiKey:  apiKey,
 	}, nil
 }
 
-func (a *APIDictionary) Find(name string) (*types.Lexeme, error) {
-	u := fmt.Sprintf("%s/lexemes/%s", baseURL, url.PathEscape(name))
-	res, err := a.httpc.Get(u)
+func (a *APIDictionary) Find(ctx context.Context, name string) (*types.Lexeme, error) {
+	u := fmt.Sprintf("%s/lexemes/%s", a.baseURL, url.PathEscape(name))
+	res, err := a.get(ctx, u)
 	if err != nil {
 		return nil, err
 	}
@@ -68,13 +74,38 @@ func (a *APIDictionary) Find(name string) (*types.Lexeme, error) {
 	return &lexeme, nil
 }
 
+// List calls the /lexemes/ API and returns all the lexemes.
+func (a *APIDictionary) List(ctx context.Context) ([]*types.Lexeme, error) {
+	res, err := a.get(ctx, a.baseURL+"/lexemes/")
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("service returned %s: %s", res.Status, body)
+	}
+
+	var lexemes []*types.Lexeme
+	if err := json.Unmarshal(body, &lexemes); err != nil {
+		log.Printf("Unable to unmarshal %s", body)
+		return nil, err
+	}
+	return lexemes, nil
+}
+
 // createRequest represents a request object for the POST /lexemes/ API.
 type createRequest struct {
 	Lexeme *types.Lexeme `json:"lexeme"`
 }
 
 // Save calls the /lexemes API and saves a new lexeme.
-func (a *APIDictionary) Save(lexeme *types.Lexeme) error {
+func (a *APIDictionary) Save(ctx context.Context, lexeme *types.Lexeme) error {
 	timestamp := time.Now()
 	if lexeme.CreatedAt == nil {
 		lexeme.CreatedAt = &timestamp
@@ -88,7 +119,7 @@ func (a *APIDictionary) Save(lexeme *types.Lexeme) error {
 		return err
 	}
 
-	resp, err := a.post(payload)
+	resp, err := a.post(ctx, payload)
 	if err != nil {
 		return err
 	}
@@ -97,10 +128,62 @@ func (a *APIDictionary) Save(lexeme *types.Lexeme) error {
 	body, err := io.ReadAll(resp.Body)
 	if resp.StatusCode != http.StatusCreated {
 		if strings.Contains(string(body), "already exists") {
-			log.Printf("%s already exists", lexeme.Name)
-			return nil
+			return types.AlreadyExists
+		}
+
+		message := fmt.Sprintf("Service returned response %v status code", resp.StatusCode)
+		if err == nil {
+			message += fmt.Sprintf(" body: %s", body)
 		}
+		return errors.New(message)
+	}
+	return nil
+}
+
+// updateRequest represents a request object for the PUT /lexemes/{name} API.
+type updateRequest struct {
+	Lexeme *types.Lexeme `json:"lexeme"`
+}
+
+// Update calls the /lexemes/{name} API and replaces an existing lexeme, bumping its updatedAt
+// timestamp.
+func (a *APIDictionary) Update(ctx context.Context, lexeme *types.Lexeme) error {
+	timestamp := time.Now()
+	lexeme.UpdatedAt = &timestamp
+	return a.update(ctx, lexeme)
+}
+
+// Upsert updates the lexeme if it exists, otherwise it saves it as a new one. Timestamps set by
+// the caller are preserved.
+func (a *APIDictionary) Upsert(ctx context.Context, lexeme *types.Lexeme) error {
+	if lexeme.UpdatedAt == nil {
+		timestamp := time.Now()
+		lexeme.UpdatedAt = &timestamp
+	}
+	err := a.update(ctx, lexeme)
+	if errors.Is(err, types.NotFound) {
+		return a.Save(ctx, lexeme)
+	}
+	return err
+}
+
+func (a *APIDictionary) update(ctx context.Context, lexeme *types.Lexeme) error {
+	payload, err := util.Serialize(updateRequest{Lexeme: lexeme})
+	if err != nil {
+		return err
+	}
+
+	resp, err := a.put(ctx, lexeme.Name, payload)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
 
+	body, err := io.ReadAll(resp.Body)
+	if resp.StatusCode == http.StatusNotFound {
+		return types.NotFound
+	}
+	if resp.StatusCode != http.StatusOK {
 		message := fmt.Sprintf("Service returned response %v status code", resp.StatusCode)
 		if err == nil {
 			message += fmt.Sprintf(" body: %s", body)
@@ -110,9 +193,17 @@ func (a *APIDictionary) Save(lexeme *types.Lexeme) error {
 	return nil
 }
 
-func (a *APIDictionary) post(payload []byte) (*http.Response, error) {
-	u := fmt.Sprintf("%s/lexemes/", baseURL)
-	req, err := http.NewRequest(http.MethodPost, u, bytes.NewReader(payload))
+func (a *APIDictionary) get(ctx context.Context, u string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	return a.httpc.Do(req)
+}
+
+func (a *APIDictionary) put(ctx context.Context, name string, payload []byte) (*http.Response, error) {
+	u := fmt.Sprintf("%s/lexemes/%s", a.baseURL, url.PathEscape(name))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, u, bytes.NewReader(payload))
 	if err != nil {
 		return nil, err
 	}
@@ -122,9 +213,9 @@ func (a *APIDictionary) post(payload []byte) (*http.Response, error) {
 	return a.httpc.Do(req)
 }
 
-func (a *APIDictionary) _delete(name string) (*http.Response, error) {
-	u := fmt.Sprintf("%s/lexemes/%s", baseURL, url.PathEscape(name))
-	req, err := http.NewRequest(http.MethodDelete, u, nil)
+func (a *APIDictionary) post(ctx context.Context, payload []byte) (*http.Response, error) {
+	u := fmt.Sprintf("%s/lexemes/", a.baseURL)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(payload))
 	if err != nil {
 		return nil, err
 	}
@@ -134,22 +225,43 @@ func (a *APIDictionary) _delete(name string) (*http.Response, error) {
 	return a.httpc.Do(req)
 }
 
-func (a *APIDictionary) Remove(name string) error {
-	_, err := a._delete(name)
+func (a *APIDictionary) _delete(ctx context.Context, name string) (*http.Response, error) {
+	u := fmt.Sprintf("%s/lexemes/%s", a.baseURL, url.PathEscape(name))
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, u, nil)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-API-KEY", a.apiKey)
 
-	if err := dictapi.Remove(name); err != nil {
+	return a.httpc.Do(req)
+}
+
+// Remove calls the /lexemes/{name} API and deletes a lexeme.
+func (a *APIDictionary) Remove(ctx context.Context, name string) error {
+	resp, err := a._delete(ctx, name)
+	if err != nil {
 		return err
 	}
+	defer resp.Body.Close()
 
-	return err
+	body, err := io.ReadAll(resp.Body)
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return types.NotFound
+	case resp.StatusCode/100 != 2:
+		message := fmt.Sprintf("Service returned response %v status code", resp.StatusCode)
+		if err == nil {
+			message += fmt.Sprintf(" body: %s", body)
+		}
+		return errors.New(message)
+	}
+	return nil
 }
 
 // Stats calls the /lexemes/stats API and returns the parsed result.
-func (a *APIDictionary) Stats() ([]types.Stat, error) {
-	res, err := a.httpc.Get(baseURL + "/stats")
+func (a *APIDictionary) Stats(ctx context.Context) ([]types.Stat, error) {
+	res, err := a.get(ctx, a.baseURL+"/stats")
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,20 @@
+package lexapi
+
+import (
+	"lexicon/lexapi/lexapitest"
+	"lexicon/types"
+	"lexicon/types/dictionarytest"
+	"testing"
+)
+
+func TestDictionary(t *testing.T) {
+	dictionarytest.Run(t, func(t *testing.T) types.Dictionary {
+		srv := lexapitest.NewServer("key")
+		t.Cleanup(srv.Close)
+		d, err := NewDictionaryAt(srv.URL, "key")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	})
+}
@@ -0,0 +1,106 @@
+// lexapitest provides a fake lexicon API for testing the lexapi client and the code that uses it.
+package lexapitest
+
+import (
+	"encoding/json"
+	"errors"
+	"lexicon/lexmem"
+	"lexicon/types"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+)
+
+// NewServer starts a lexicon API that keeps the words in memory and accepts changes made with
+// apiKey. The caller must close it.
+func NewServer(apiKey string) *httptest.Server {
+	return httptest.NewServer(Handler(lexmem.New(), apiKey))
+}
+
+// lexemeRequest is the body of the POST /lexemes/ and PUT /lexemes/{name} requests.
+type lexemeRequest struct {
+	Lexeme *types.Lexeme `json:"lexeme"`
+}
+
+// Handler serves the lexicon API backed by d. Requests that change d must have apiKey in their
+// X-API-KEY header.
+func Handler(d types.Dictionary, apiKey string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/stats", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		stats, err := d.Stats(r.Context())
+		reply(w, http.StatusOK, stats, err)
+	})
+	mux.HandleFunc("/lexemes/", func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, "/lexemes/")
+		if r.Method != http.MethodGet && r.Header.Get("X-API-KEY") != apiKey {
+			http.Error(w, "invalid API key", http.StatusUnauthorized)
+			return
+		}
+
+		switch {
+		case r.Method == http.MethodGet && name == "":
+			lexemes, err := d.List(r.Context())
+			reply(w, http.StatusOK, lexemes, err)
+		case r.Method == http.MethodGet:
+			lexeme, err := d.Find(r.Context(), name)
+			reply(w, http.StatusOK, lexeme, err)
+		case r.Method == http.MethodPost && name == "":
+			lexeme, ok := readLexeme(w, r)
+			if ok {
+				reply(w, http.StatusCreated, lexeme, d.Save(r.Context(), lexeme))
+			}
+		case r.Method == http.MethodPut && name != "":
+			lexeme, ok := readLexeme(w, r)
+			if !ok {
+				return
+			}
+			lexeme.Name = name
+			// The client sets the timestamps, Upsert keeps them.
+			_, err := d.Find(r.Context(), name)
+			if err == nil {
+				err = d.Upsert(r.Context(), lexeme)
+			}
+			reply(w, http.StatusOK, lexeme, err)
+		case r.Method == http.MethodDelete && name != "":
+			reply(w, http.StatusNoContent, nil, d.Remove(r.Context(), name))
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+	return mux
+}
+
+func readLexeme(w http.ResponseWriter, r *http.Request) (*types.Lexeme, bool) {
+	var req lexemeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Lexeme == nil {
+		http.Error(w, "invalid lexeme", http.StatusBadRequest)
+		return nil, false
+	}
+	return req.Lexeme, true
+}
+
+// reply writes v as JSON with the status, or the error.
+func reply(w http.ResponseWriter, status int, v interface{}, err error) {
+	switch {
+	case errors.Is(err, types.NotFound):
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	case errors.Is(err, types.AlreadyExists):
+		http.Error(w, "the lexeme already exists", http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if status == http.StatusNoContent {
+		w.WriteHeader(status)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,137 @@
+package lexdb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// backupPages is the number of pages copied at a time by the online backup. Other connections can
+// use the database between steps.
+const backupPages = 256
+
+// Backup copies the database to a new SQLite file in path with the online backup API of SQLite, so
+// it's consistent even if other processes write to the database meanwhile.
+func (x *Lexicon) Backup(ctx context.Context, path string) error {
+	dest, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return err
+	}
+	defer dest.Close()
+	return copyDatabase(ctx, dest, x.db)
+}
+
+// Verify checks that the SQLite file in path is a sound lexicon database with a schema this
+// package supports.
+func Verify(ctx context.Context, path string) error {
+	src, err := openReadOnly(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	return verify(ctx, src, path)
+}
+
+// Restore replaces the content of the database with the SQLite file in path, see Verify. Older
+// schemas are upgraded.
+func (x *Lexicon) Restore(ctx context.Context, path string) error {
+	src, err := openReadOnly(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	if err := verify(ctx, src, path); err != nil {
+		return err
+	}
+
+	if err := copyDatabase(ctx, x.db, src); err != nil {
+		return err
+	}
+	return migrate(x.db)
+}
+
+// openReadOnly opens a database without creating it if it doesn't exist.
+func openReadOnly(path string) (*sql.DB, error) {
+	return sql.Open("sqlite3", "file:"+url.PathEscape(path)+"?mode=ro")
+}
+
+func verify(ctx context.Context, db *sql.DB, path string) error {
+	version, err := SchemaVersion(ctx, db)
+	if err != nil {
+		return fmt.Errorf("%s isn't a lexicon database: %s", path, err)
+	}
+	if version > schemaVersion {
+		return fmt.Errorf("%s has schema version %d, newer than the supported version %d", path, version, schemaVersion)
+	}
+	var check string
+	if err := db.QueryRowContext(ctx, `PRAGMA quick_check`).Scan(&check); err != nil {
+		return err
+	}
+	if check != "ok" {
+		return fmt.Errorf("%s is corrupt: %s", path, check)
+	}
+	return nil
+}
+
+// SchemaVersion returns the schema version of a lexicon database. It fails if db doesn't have the
+// lexicon table.
+func SchemaVersion(ctx context.Context, db *sql.DB) (int, error) {
+	var n int
+	err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'lexicon'`).Scan(&n)
+	if err != nil {
+		return 0, err
+	}
+	if n == 0 {
+		return 0, errors.New("missing lexicon table")
+	}
+	var version int
+	if err := db.QueryRowContext(ctx, `PRAGMA user_version`).Scan(&version); err != nil {
+		return 0, err
+	}
+	return version, nil
+}
+
+// copyDatabase copies the main database of src over the one of dest.
+func copyDatabase(ctx context.Context, dest, src *sql.DB) error {
+	destConn, err := dest.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer destConn.Close()
+	srcConn, err := src.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer srcConn.Close()
+
+	return destConn.Raw(func(d interface{}) error {
+		return srcConn.Raw(func(s interface{}) error {
+			b, err := d.(*sqlite3.SQLiteConn).Backup("main", s.(*sqlite3.SQLiteConn), "main")
+			if err != nil {
+				return err
+			}
+			for {
+				done, err := b.Step(backupPages)
+				if err != nil {
+					_ = b.Finish()
+					return err
+				}
+				if done {
+					return b.Finish()
+				}
+				// The database is busy or there are pages left, give other connections a chance.
+				select {
+				case <-ctx.Done():
+					_ = b.Finish()
+					return ctx.Err()
+				case <-time.After(10 * time.Millisecond):
+				}
+			}
+		})
+	})
+}
@@ -0,0 +1,132 @@
+package lexdb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// defaultParams are the go-sqlite3 parameters used unless the data source name sets them. WAL
+// journaling lets readers and a writer use the database at the same time, the busy timeout makes
+// connections wait for the lock instead of failing with "database is locked", and immediate
+// transactions take the write lock when they begin so they can't deadlock when they write later.
+// aliases are other names of the parameters accepted by the driver.
+var defaultParams = []struct {
+	name, value string
+	aliases     []string
+}{
+	{"_journal_mode", "WAL", []string{"_journal"}},
+	{"_busy_timeout", "5000", []string{"_timeout"}},
+	{"_txlock", "immediate", nil},
+}
+
+// poolSettings are the settings of the connection pool, set with the max_open_conns and
+// max_idle_conns parameters of the data source name.
+type poolSettings struct {
+	maxOpen int // Zero means no limit.
+	maxIdle int
+}
+
+// Retries of operations that fail because the database is busy, after waiting for the busy timeout.
+const (
+	busyRetries = 5
+	busyBackoff = 50 * time.Millisecond
+)
+
+// dataSource returns the data source name passed to the driver, with the default parameters added,
+// and the settings of the pool, which the driver doesn't know about.
+func dataSource(sourceName string) (string, poolSettings, error) {
+	pool := poolSettings{maxOpen: 0, maxIdle: 2}
+	path, query := sourceName, ""
+	if i := strings.IndexByte(sourceName, '?'); i >= 0 {
+		path, query = sourceName[:i], sourceName[i+1:]
+	}
+	params, err := url.ParseQuery(query)
+	if err != nil {
+		return "", pool, fmt.Errorf("invalid data source name %q: %s", sourceName, err)
+	}
+
+	for _, p := range []struct {
+		name  string
+		value *int
+	}{{"max_open_conns", &pool.maxOpen}, {"max_idle_conns", &pool.maxIdle}} {
+		if v := params.Get(p.name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				return "", pool, fmt.Errorf("invalid %s %q", p.name, v)
+			}
+			*p.value = n
+		}
+		params.Del(p.name)
+	}
+
+	for _, p := range defaultParams {
+		set := params.Get(p.name) != ""
+		for _, alias := range p.aliases {
+			set = set || params.Get(alias) != ""
+		}
+		if !set {
+			params.Set(p.name, p.value)
+		}
+	}
+	return path + "?" + params.Encode(), pool, nil
+}
+
+// FilePath returns the file of the database in a data source name.
+func FilePath(sourceName string) string {
+	if i := strings.IndexByte(sourceName, '?'); i >= 0 {
+		sourceName = sourceName[:i]
+	}
+	if strings.HasPrefix(sourceName, "file:") {
+		if p, err := url.PathUnescape(strings.TrimPrefix(sourceName, "file:")); err == nil {
+			return p
+		}
+	}
+	return sourceName
+}
+
+// isBusy reports whether err means that another connection holds the lock of the database.
+func isBusy(err error) bool {
+	var serr sqlite3.Error
+	return errors.As(err, &serr) && (serr.Code == sqlite3.ErrBusy || serr.Code == sqlite3.ErrLocked)
+}
+
+// retry runs fn again, with an increasing delay, while it fails because the database is busy.
+func retry(ctx context.Context, fn func() error) error {
+	delay := busyBackoff
+	for i := 0; ; i++ {
+		err := fn()
+		if err == nil || !isBusy(err) || i == busyRetries {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// transaction runs fn in a transaction, which is committed if fn succeeds and rolled back
+// otherwise. The whole transaction is retried if the database is busy.
+func (x *Lexicon) transaction(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	return retry(ctx, func() error {
+		tx, err := x.db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		if err := fn(tx); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+		return tx.Commit()
+	})
+}
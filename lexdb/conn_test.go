@@ -0,0 +1,213 @@
+package lexdb
+
+import (
+	"context"
+	"fmt"
+	"lexicon/types"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// writerEnv makes the test binary run writeWords on the database in the variable instead of the
+// tests, so that the tests can write to a database from another process.
+const writerEnv = "LEXDB_TEST_WRITER"
+
+// Words written by every writer of TestConcurrentWrites.
+const wordsPerWriter = 50
+
+func TestMain(m *testing.M) {
+	if path := os.Getenv(writerEnv); path != "" {
+		x, err := Open(path)
+		if err == nil {
+			err = writeWords(context.Background(), x, "process")
+			if cerr := x.Close(); err == nil {
+				err = cerr
+			}
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// writeWords saves, upserts and modifies wordsPerWriter words named after writer, and adds writer
+// to the tags of the shared word.
+func writeWords(ctx context.Context, x *Lexicon, writer string) error {
+	for i := 0; i < wordsPerWriter; i++ {
+		name := fmt.Sprintf("%s-%d", writer, i)
+		if err := x.Save(ctx, &types.Lexeme{Name: name, Source: writer}); err != nil {
+			return fmt.Errorf("save %s: %w", name, err)
+		}
+		if err := x.Upsert(ctx, &types.Lexeme{Name: name, Source: writer, Notes: "upserted"}); err != nil {
+			return fmt.Errorf("upsert %s: %w", name, err)
+		}
+		err := x.Modify(ctx, name, func(lexeme *types.Lexeme) error {
+			lexeme.Tags = append(lexeme.Tags, "modified")
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("modify %s: %w", name, err)
+		}
+	}
+	err := x.Modify(ctx, "shared", func(lexeme *types.Lexeme) error {
+		lexeme.Tags = append(lexeme.Tags, writer)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("modify shared: %w", err)
+	}
+	return nil
+}
+
+// TestConcurrentWrites writes to one database from goroutines sharing a Lexicon and from another
+// process at the same time. None of them may fail because the database is locked, and no write may
+// be lost.
+func TestConcurrentWrites(t *testing.T) {
+	const goroutines = 8
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "lexicon.sqlite")
+	x, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer x.Close()
+	if err := x.Save(ctx, &types.Lexeme{Name: "shared"}); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command(os.Args[0])
+	cmd.Env = append(os.Environ(), writerEnv+"="+path)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+
+	// Start the goroutines once the process writes, so that they write at the same time.
+	for {
+		if _, err := x.Find(ctx, "process-0"); err == nil {
+			break
+		}
+		select {
+		case err := <-exited:
+			t.Fatalf("writer process exited before writing: %v: %s", err, strings.TrimSpace(stderr.String()))
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+
+	errs := make(chan error, goroutines)
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func(writer string) {
+			defer wg.Done()
+			errs <- writeWords(ctx, x, writer)
+		}(fmt.Sprintf("goroutine%d", i))
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("writer failed: %s", err)
+		}
+	}
+	if err := <-exited; err != nil {
+		t.Errorf("writer process failed: %s: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	if strings.Contains(stderr.String(), "database is locked") {
+		t.Errorf("writer process logged: %s", stderr.String())
+	}
+
+	var words, modified int
+	err = x.db.QueryRow(`SELECT COUNT(*), COUNT(CASE WHEN notes = 'upserted' AND tags = 'modified' THEN 1 END) FROM lexicon`).
+		Scan(&words, &modified)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (goroutines+1)*wordsPerWriter + 1; words != want {
+		t.Errorf("got %d words, want %d", words, want)
+	}
+	if want := (goroutines + 1) * wordsPerWriter; modified != want {
+		t.Errorf("got %d upserted and modified words, want %d", modified, want)
+	}
+
+	shared, err := x.Find(ctx, "shared")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"process"}
+	for i := 0; i < goroutines; i++ {
+		want = append(want, fmt.Sprintf("goroutine%d", i))
+	}
+	sort.Strings(want)
+	got := append([]string(nil), shared.Tags...)
+	sort.Strings(got)
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("tags of the shared word are %v, want %v", got, want)
+	}
+}
+
+func TestDataSource(t *testing.T) {
+	const defaults = "_busy_timeout=5000&_journal_mode=WAL&_txlock=immediate"
+	tests := []struct {
+		sourceName string
+		want       string
+		pool       poolSettings
+		err        bool
+	}{
+		{sourceName: "lexicon.sqlite", want: "lexicon.sqlite?" + defaults, pool: poolSettings{0, 2}},
+		{sourceName: "lexicon.sqlite?", want: "lexicon.sqlite?" + defaults, pool: poolSettings{0, 2}},
+		{
+			sourceName: "file:my%20lexicon.sqlite?mode=rwc",
+			want:       "file:my%20lexicon.sqlite?" + defaults + "&mode=rwc",
+			pool:       poolSettings{0, 2},
+		},
+		{
+			sourceName: "lexicon.sqlite?_busy_timeout=100&_journal_mode=DELETE&_txlock=deferred",
+			want:       "lexicon.sqlite?_busy_timeout=100&_journal_mode=DELETE&_txlock=deferred",
+			pool:       poolSettings{0, 2},
+		},
+		// Aliases are kept as they are and replace the defaults.
+		{
+			sourceName: "lexicon.sqlite?_timeout=100&_journal=TRUNCATE",
+			want:       "lexicon.sqlite?_journal=TRUNCATE&_timeout=100&_txlock=immediate",
+			pool:       poolSettings{0, 2},
+		},
+		{
+			sourceName: "lexicon.sqlite?max_open_conns=4&max_idle_conns=1",
+			want:       "lexicon.sqlite?" + defaults,
+			pool:       poolSettings{4, 1},
+		},
+		{sourceName: "lexicon.sqlite?max_open_conns=0&max_idle_conns=0", want: "lexicon.sqlite?" + defaults},
+		{sourceName: "lexicon.sqlite?max_open_conns=-1", err: true},
+		{sourceName: "lexicon.sqlite?max_idle_conns=many", err: true},
+		{sourceName: "lexicon.sqlite?_timeout=%zz", err: true},
+	}
+	for _, tt := range tests {
+		got, pool, err := dataSource(tt.sourceName)
+		if tt.err {
+			if err == nil {
+				t.Errorf("dataSource(%q) returned %q, want an error", tt.sourceName, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("dataSource(%q) returned %v", tt.sourceName, err)
+			continue
+		}
+		if got != tt.want || pool != tt.pool {
+			t.Errorf("dataSource(%q) = %q, %+v, want %q, %+v", tt.sourceName, got, pool, tt.want, tt.pool)
+		}
+	}
+}
@@ -0,0 +1,300 @@
+package lexdb
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"lexicon/types"
+	"lexicon/util"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Kinds of problems found by Check.
+const (
+	MillisecondTimestamps = "milliseconds"
+	ZeroTimestamps        = "zero-timestamp"
+	SwappedTimestamps     = "swapped-timestamps"
+	BadDefinition         = "bad-definition"
+	DuplicateName         = "duplicate"
+	UnnormalizedName      = "unnormalized-name"
+	Orphan                = "orphan"
+)
+
+// msThreshold separates timestamps in seconds from timestamps in milliseconds: in seconds it's in
+// the year 5138, in milliseconds in 1973.
+const msThreshold = 100_000_000_000
+
+// Problem is an inconsistency in the rows of the lexicon table.
+type Problem struct {
+	Name   string `json:"name"`          // Name of the word as stored.
+	Kind   string `json:"kind"`          // One of the kinds above.
+	Detail string `json:"detail"`        // What's wrong.
+	Fix    string `json:"fix,omitempty"` // What Repair does about it, empty if Repair can't fix it.
+	apply  func(ctx context.Context, tx *sql.Tx) error
+}
+
+// row is a row of the lexicon table as stored.
+type row struct {
+	name, definition, source string
+	createdAt, updatedAt     int64
+	tags, notes              string
+}
+
+// Check scans every row of the lexicon table and returns the problems found. Names are expected to
+// be trimmed and lowercase, as the lexicon saves them, timestamps to be Unix seconds with createdAt
+// not after updatedAt, and definitions to be JSON documents with at least one entry.
+func (x *Lexicon) Check(ctx context.Context) ([]Problem, int, error) {
+	rows, err := x.rows(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var problems []Problem
+	for i := range rows {
+		if strings.TrimSpace(rows[i].name) == "" {
+			// Orphans are deleted, nothing else about them matters.
+			continue
+		}
+		problems = append(problems, checkTimestamps(&rows[i])...)
+		if err := checkDefinition(rows[i].definition); err != nil {
+			problems = append(problems, Problem{Name: rows[i].name, Kind: BadDefinition, Detail: err.Error()})
+		}
+	}
+	problems = append(problems, checkNames(rows)...)
+	return problems, len(rows), nil
+}
+
+// Repair fixes the problems found by Check that can be fixed without fetching definitions again, in
+// a single transaction. It returns the problems that were fixed.
+func (x *Lexicon) Repair(ctx context.Context) ([]Problem, error) {
+	problems, _, err := x.Check(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var fixed []Problem
+	err = x.transaction(ctx, func(tx *sql.Tx) error {
+		fixed = nil
+		for _, p := range problems {
+			if p.Fix == "" {
+				continue
+			}
+			// The timestamps of a row are fixed together, by the apply of its first problem.
+			if p.apply != nil {
+				if err := p.apply(ctx, tx); err != nil {
+					return fmt.Errorf("unable to fix %s of %q: %w", p.Kind, p.Name, err)
+				}
+			}
+			fixed = append(fixed, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return fixed, nil
+}
+
+func (x *Lexicon) rows(ctx context.Context) ([]row, error) {
+	return readRows(ctx, x.db, `SELECT `+columns+` FROM lexicon ORDER BY name`)
+}
+
+// readRows returns the rows of a query of the columns of the lexicon table.
+func readRows(ctx context.Context, q querier, query string) ([]row, error) {
+	rows, err := q.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []row
+	for rows.Next() {
+		var r row
+		var def, source, tags, notes sql.NullString
+		if err := rows.Scan(&r.name, &def, &source, &r.createdAt, &r.updatedAt, &tags, &notes); err != nil {
+			return nil, err
+		}
+		r.definition, r.source, r.tags, r.notes = def.String, source.String, tags.String, notes.String
+		res = append(res, r)
+	}
+	return res, rows.Err()
+}
+
+// checkTimestamps returns the problems with the timestamps of r and normalizes them in r, so that
+// later checks see the fixed values.
+func checkTimestamps(r *row) []Problem {
+	var problems []Problem
+	name := r.name
+	created, updated := r.createdAt, r.updatedAt
+	if created >= msThreshold || updated >= msThreshold {
+		if created >= msThreshold {
+			created /= 1000
+		}
+		if updated >= msThreshold {
+			updated /= 1000
+		}
+		problems = append(problems, Problem{
+			Name:   name,
+			Kind:   MillisecondTimestamps,
+			Detail: fmt.Sprintf("createdAt %d and updatedAt %d aren't both in seconds", r.createdAt, r.updatedAt),
+			Fix:    "convert them to seconds",
+		})
+	}
+	if created == 0 || updated == 0 {
+		p := Problem{Name: name, Kind: ZeroTimestamps, Detail: "createdAt or updatedAt is zero"}
+		if created != 0 || updated != 0 {
+			p.Fix = "use the other timestamp"
+			if created == 0 {
+				created = updated
+			} else {
+				updated = created
+			}
+		}
+		problems = append(problems, p)
+	}
+	if created > updated {
+		problems = append(problems, Problem{
+			Name:   name,
+			Kind:   SwappedTimestamps,
+			Detail: fmt.Sprintf("created on %s, after its update on %s", formatUnix(created), formatUnix(updated)),
+			Fix:    "swap them",
+		})
+		created, updated = updated, created
+	}
+
+	if len(problems) > 0 && (created != r.createdAt || updated != r.updatedAt) {
+		problems[0].apply = func(ctx context.Context, tx *sql.Tx) error {
+			_, err := tx.ExecContext(ctx, `UPDATE lexicon SET createdAt = ?, updatedAt = ? WHERE name = ?`, created, updated, name)
+			return err
+		}
+		r.createdAt, r.updatedAt = created, updated
+	}
+	return problems
+}
+
+// checkDefinition returns why def isn't a usable definition, if it isn't.
+func checkDefinition(def string) error {
+	if strings.TrimSpace(def) == "" {
+		return fmt.Errorf("the definition is empty")
+	}
+	var d types.Definition
+	if err := json.Unmarshal([]byte(def), &d); err != nil {
+		return fmt.Errorf("the definition isn't valid: %s", err)
+	}
+	if len(d.Entries) == 0 {
+		return fmt.Errorf("the definition has no entries")
+	}
+	return nil
+}
+
+// checkNames finds rows without a name, names that aren't trimmed and lowercase and therefore can't
+// be found, and names that are the same word once normalized.
+func checkNames(rows []row) []Problem {
+	groups := make(map[string][]row)
+	var keys []string
+	var problems []Problem
+	for _, r := range rows {
+		key := strings.ToLower(strings.TrimSpace(r.name))
+		if key == "" {
+			name := r.name
+			problems = append(problems, Problem{
+				Name:   name,
+				Kind:   Orphan,
+				Detail: "the row has no name, no command can reach it",
+				Fix:    "delete it",
+				apply: func(ctx context.Context, tx *sql.Tx) error {
+					_, err := tx.ExecContext(ctx, `DELETE FROM lexicon WHERE name = ?`, name)
+					return err
+				},
+			})
+			continue
+		}
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], r)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		group := groups[key]
+		if len(group) == 1 {
+			if name := group[0].name; name != key {
+				problems = append(problems, Problem{
+					Name:   name,
+					Kind:   UnnormalizedName,
+					Detail: "the name isn't trimmed and lowercase like the names commands look up, so it can't be found",
+					Fix:    fmt.Sprintf("rename it to %q", key),
+					apply: func(ctx context.Context, tx *sql.Tx) error {
+						_, err := tx.ExecContext(ctx, `UPDATE lexicon SET name = ? WHERE name = ?`, key, name)
+						return err
+					},
+				})
+			}
+			continue
+		}
+
+		var names []string
+		for _, r := range group {
+			names = append(names, fmt.Sprintf("%q", r.name))
+		}
+		merged := mergeRows(key, group)
+		problems = append(problems, Problem{
+			Name:   key,
+			Kind:   DuplicateName,
+			Detail: fmt.Sprintf("the word is stored %d times: %s", len(group), strings.Join(names, ", ")),
+			Fix:    "merge them, keeping the earliest date, every tag and note and a valid definition",
+			apply: func(ctx context.Context, tx *sql.Tx) error {
+				for _, r := range group {
+					if _, err := tx.ExecContext(ctx, `DELETE FROM lexicon WHERE name = ?`, r.name); err != nil {
+						return err
+					}
+				}
+				_, err := tx.ExecContext(ctx, `INSERT INTO lexicon(`+columns+`) values(?,?,?,?,?,?,?)`,
+					merged.name, merged.definition, merged.source, merged.createdAt, merged.updatedAt, merged.tags, merged.notes)
+				return err
+			},
+		})
+	}
+	return problems
+}
+
+// mergeRows merges rows that hold the same word. The definition comes from the row named key if
+// it's valid, otherwise from the first row with a valid definition.
+func mergeRows(key string, group []row) row {
+	best := group[0]
+	for _, r := range group {
+		if checkDefinition(r.definition) == nil && (r.name == key || checkDefinition(best.definition) != nil) {
+			best = r
+		}
+	}
+
+	merged := row{name: key, definition: best.definition, source: best.source}
+	var tags, notes []string
+	for _, r := range group {
+		if merged.createdAt == 0 || (r.createdAt != 0 && r.createdAt < merged.createdAt) {
+			merged.createdAt = r.createdAt
+		}
+		if r.updatedAt > merged.updatedAt {
+			merged.updatedAt = r.updatedAt
+		}
+		for _, t := range splitTags(r.tags) {
+			if !util.Contains(tags, t) {
+				tags = append(tags, t)
+			}
+		}
+		if n := strings.TrimSpace(r.notes); n != "" && !util.Contains(notes, n) {
+			notes = append(notes, n)
+		}
+	}
+	merged.tags = strings.Join(tags, ",")
+	merged.notes = strings.Join(notes, "\n")
+	return merged
+}
+
+func formatUnix(ts int64) string {
+	return time.Unix(ts, 0).Format("2006-01-02 15:04:05")
+}
@@ -1,36 +1,63 @@
 package lexdb
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
 	"lexicon/types"
 	"log"
-	"os"
+	"strings"
 	"time"
+
+	"github.com/mattn/go-sqlite3"
 )
 
 type Lexicon struct {
 	db *sql.DB
 }
 
-// NewDictionary returns a new Dictionary backed by an SQLite database.
-func NewDictionary() (types.Dictionary, error) {
-	sourceName := os.Getenv("DATA_SOURCE_NAME")
+// NewDictionary returns a new Dictionary backed by the SQLite database in sourceName.
+func NewDictionary(sourceName string) (types.Dictionary, error) {
+	x, err := Open(sourceName)
+	if err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// Open opens the SQLite database in sourceName and upgrades its schema if needed. sourceName is a
+// file name optionally followed by the parameters of the go-sqlite3 driver and of the connection
+// pool, e.g. "lexicon.sqlite?_busy_timeout=10000&max_open_conns=2", see dataSource.
+func Open(sourceName string) (*Lexicon, error) {
 	if len(sourceName) == 0 {
 		return nil, errors.New("missing data source name")
 	}
-	db, err := sql.Open("sqlite3", sourceName)
+	dsn, pool, err := dataSource(sourceName)
+	if err != nil {
+		return nil, err
+	}
+	db, err := sql.Open("sqlite3", dsn)
 	if err != nil {
 		return nil, fmt.Errorf("unable to create database: %s", err)
 	}
+	db.SetMaxOpenConns(pool.maxOpen)
+	db.SetMaxIdleConns(pool.maxIdle)
+	if err := migrate(db); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
 	return &Lexicon{db: db}, nil
 }
 
 // Find finds and returns a name in the database or returns error if the name
 // does not exist in the database.
-func (x *Lexicon) Find(name string) (*types.Lexeme, error) {
-	rows, err := x.db.Query(`SELECT * FROM lexicon WHERE name = ?`, name)
+func (x *Lexicon) Find(ctx context.Context, name string) (*types.Lexeme, error) {
+	return find(ctx, x.db, name)
+}
+
+func find(ctx context.Context, q querier, name string) (*types.Lexeme, error) {
+	rows, err := q.QueryContext(ctx, `SELECT `+columns+` FROM lexicon WHERE name = ?`, name)
 	if err != nil {
 		log.Printf("Unable to query the database: %s", err)
 		return nil, err
@@ -44,18 +71,24 @@ func (x *Lexicon) Find(name string) (*types.Lexeme, error) {
 	return readRecord(rows)
 }
 
-func (x *Lexicon) exists(name string) bool {
-	rows, err := x.db.Query(`SELECT * FROM lexicon WHERE name = ?`, name)
+// querier runs statements in the database or in a transaction.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+func exists(ctx context.Context, q querier, name string) (bool, error) {
+	rows, err := q.QueryContext(ctx, `SELECT name FROM lexicon WHERE name = ?`, name)
 	if err != nil {
 		log.Printf("Unable to query the database: %s", err)
-		return false
+		return false, err
 	}
 	defer rows.Close()
-	return rows.Next()
+	return rows.Next(), rows.Err()
 }
 
-func (x *Lexicon) selectRandom() (*types.Lexeme, error) {
-	rows, err := x.db.Query(`SELECT * FROM lexicon ORDER BY RANDOM() LIMIT 1`)
+func (x *Lexicon) selectRandom(ctx context.Context) (*types.Lexeme, error) {
+	rows, err := x.db.QueryContext(ctx, `SELECT `+columns+` FROM lexicon ORDER BY RANDOM() LIMIT 1`)
 	if err != nil {
 		log.Printf("Unable to query lexicon table: %s", err)
 		return nil, err
@@ -70,7 +103,13 @@ func (x *Lexicon) selectRandom() (*types.Lexeme, error) {
 }
 
 // Save add lexeme to the database. Returns error if the operation fails.
-func (x *Lexicon) Save(lexeme *types.Lexeme) error {
+func (x *Lexicon) Save(ctx context.Context, lexeme *types.Lexeme) error {
+	return retry(ctx, func() error {
+		return save(ctx, x.db, lexeme)
+	})
+}
+
+func save(ctx context.Context, q querier, lexeme *types.Lexeme) error {
 	timestamp := time.Now()
 	if lexeme.CreatedAt == nil {
 		lexeme.CreatedAt = &timestamp
@@ -79,29 +118,116 @@ func (x *Lexicon) Save(lexeme *types.Lexeme) error {
 		lexeme.UpdatedAt = &timestamp
 	}
 
-	stmt, err := x.db.Prepare(
-		`INSERT INTO lexicon(name, definition, source, createdAt, updatedAt) values(?,?,?,?,?)`)
-	if err != nil {
-		return fmt.Errorf("unable to insert prepare statement: %s", err)
-	}
-	defer stmt.Close()
-
-	_, err = stmt.Exec(
+	_, err := q.ExecContext(ctx, `INSERT INTO lexicon(`+columns+`) values(?,?,?,?,?,?,?)`,
 		lexeme.Name,
 		lexeme.Definition,
 		lexeme.Source,
 		lexeme.CreatedAt.Unix(),
 		lexeme.UpdatedAt.Unix(),
+		strings.Join(lexeme.Tags, ","),
+		lexeme.Notes,
 	)
 	if err != nil {
+		var serr sqlite3.Error
+		if errors.As(err, &serr) && serr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey {
+			return types.AlreadyExists
+		}
 		log.Printf("Unable to insert record: %s", err)
 		return err
 	}
 	return nil
 }
 
-func (x *Lexicon) All() ([]*types.Lexeme, error) {
-	rows, err := x.db.Query("SELECT * FROM lexicon")
+// Update replaces the definition, source, tags and notes of an existing lexeme, and its createdAt
+// timestamp if the lexeme sets it, and sets its updatedAt timestamp to the current time. Returns
+// types.NotFound if the lexeme does not exist.
+func (x *Lexicon) Update(ctx context.Context, lexeme *types.Lexeme) error {
+	timestamp := time.Now()
+	lexeme.UpdatedAt = &timestamp
+	return retry(ctx, func() error {
+		return update(ctx, x.db, lexeme)
+	})
+}
+
+// Upsert saves the lexeme if it doesn't exist yet, otherwise it updates the existing record.
+// Unlike Update, the timestamps provided by the caller are written as is, which allows importing
+// words while keeping their original dates.
+func (x *Lexicon) Upsert(ctx context.Context, lexeme *types.Lexeme) error {
+	return x.transaction(ctx, func(tx *sql.Tx) error {
+		found, err := exists(ctx, tx, lexeme.Name)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return save(ctx, tx, lexeme)
+		}
+		if lexeme.UpdatedAt == nil {
+			timestamp := time.Now()
+			lexeme.UpdatedAt = &timestamp
+		}
+		return update(ctx, tx, lexeme)
+	})
+}
+
+// Modify changes a saved lexeme with fn and updates it, in a transaction so that changes made
+// meanwhile by other processes aren't lost. Nothing is written if fn fails.
+func (x *Lexicon) Modify(ctx context.Context, name string, fn func(lexeme *types.Lexeme) error) error {
+	return x.transaction(ctx, func(tx *sql.Tx) error {
+		lexeme, err := find(ctx, tx, name)
+		if err != nil {
+			return err
+		}
+		if err := fn(lexeme); err != nil {
+			return err
+		}
+		timestamp := time.Now()
+		lexeme.UpdatedAt = &timestamp
+		return update(ctx, tx, lexeme)
+	})
+}
+
+// update writes the lexeme to an existing record. The createdAt timestamp is only overwritten
+// when it is set.
+func update(ctx context.Context, q querier, lexeme *types.Lexeme) error {
+	var createdAt interface{}
+	if lexeme.CreatedAt != nil {
+		createdAt = lexeme.CreatedAt.Unix()
+	}
+
+	res, err := q.ExecContext(ctx,
+		`UPDATE lexicon SET definition = ?, source = ?, createdAt = COALESCE(?, createdAt), updatedAt = ?,
+		tags = ?, notes = ? WHERE name = ?`,
+		lexeme.Definition,
+		lexeme.Source,
+		createdAt,
+		lexeme.UpdatedAt.Unix(),
+		strings.Join(lexeme.Tags, ","),
+		lexeme.Notes,
+		lexeme.Name,
+	)
+	if err != nil {
+		log.Printf("Unable to update record: %s", err)
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return types.NotFound
+	}
+	return nil
+}
+
+// List returns all the lexemes in the database.
+func (x *Lexicon) List(ctx context.Context) ([]*types.Lexeme, error) {
+	return x.All(ctx)
+}
+
+// All returns all the lexemes in the database. Records that can't be read are skipped.
+func (x *Lexicon) All(ctx context.Context) ([]*types.Lexeme, error) {
+	rows, err := x.db.QueryContext(ctx, `SELECT `+columns+` FROM lexicon`)
 	if err != nil {
 		log.Printf("Unable to query lexicon table: %s", err)
 		return nil, err
@@ -120,13 +246,49 @@ func (x *Lexicon) All() ([]*types.Lexeme, error) {
 	return all, nil
 }
 
-func (x *Lexicon) Remove(name string) error {
-	return errors.New("not implemented yet")
+// Remove deletes a lexeme from the database. Returns types.NotFound if it doesn't exist.
+func (x *Lexicon) Remove(ctx context.Context, name string) error {
+	return retry(ctx, func() error {
+		res, err := x.db.ExecContext(ctx, `DELETE FROM lexicon WHERE name = ?`, name)
+		if err != nil {
+			log.Printf("Unable to delete record: %s", err)
+			return err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return types.NotFound
+		}
+		return nil
+	})
 }
 
-// Stats returns stats from the database.
-func (x *Lexicon) Stats() ([]types.Stat, error) {
-	return nil, errors.New("not implemented yet")
+// Stats returns the number of words in the database and how many of them were added today, in the
+// last 7 days and this month. Days start at midnight in the time zone of ctx, see types.Location.
+func (x *Lexicon) Stats(ctx context.Context) ([]types.Stat, error) {
+	loc := types.Location(ctx)
+	y, m, d := time.Now().In(loc).Date()
+	today := time.Date(y, m, d, 0, 0, 0, 0, loc)
+	month := time.Date(y, m, 1, 0, 0, 0, 0, loc)
+
+	var total, day, week, monthly int64
+	err := x.db.QueryRowContext(ctx,
+		`SELECT COUNT(*), COALESCE(SUM(createdAt >= ?), 0), COALESCE(SUM(createdAt >= ?), 0),
+		COALESCE(SUM(createdAt >= ?), 0) FROM lexicon`,
+		today.Unix(), today.AddDate(0, 0, -6).Unix(), month.Unix(),
+	).Scan(&total, &day, &week, &monthly)
+	if err != nil {
+		log.Printf("Unable to query lexicon table: %s", err)
+		return nil, err
+	}
+	return []types.Stat{
+		{Name: "Words", Value: float64(total)},
+		{Name: "Added today", Value: float64(day)},
+		{Name: "Added in the last 7 days", Value: float64(week)},
+		{Name: "Added this month", Value: float64(monthly)},
+	}, nil
 }
 
 // Close closes the connection to the database.
@@ -143,17 +305,31 @@ func (x *Lexicon) Close() error {
 func readRecord(rows *sql.Rows) (*types.Lexeme, error) {
 	var name, def, source string
 	var createdAt, updatedAt int64
-	if err := rows.Scan(&name, &def, &source, &createdAt, &updatedAt); err != nil {
+	var tags, notes sql.NullString
+	if err := rows.Scan(&name, &def, &source, &createdAt, &updatedAt, &tags, &notes); err != nil {
 		return nil, err
 	}
 
-	cat := time.Unix(updatedAt, 0)
-	uat := time.Unix(createdAt, 0)
+	cat := time.Unix(createdAt, 0)
+	uat := time.Unix(updatedAt, 0)
 	return &types.Lexeme{
 		Name:       name,
 		Definition: def,
 		Source:     source,
 		CreatedAt:  &cat,
 		UpdatedAt:  &uat,
+		Tags:       splitTags(tags.String),
+		Notes:      notes.String,
 	}, nil
 }
+
+// splitTags parses the comma-separated list of tags stored in the tags column.
+func splitTags(s string) []string {
+	var tags []string
+	for _, t := range strings.Split(s, ",") {
+		if t = strings.TrimSpace(t); len(t) > 0 {
+			tags = append(tags, t)
+		}
+	}
+	return tags
+}
@@ -0,0 +1,18 @@
+package lexdb
+
+import (
+	"lexicon/types"
+	"lexicon/types/dictionarytest"
+	"path/filepath"
+	"testing"
+)
+
+func TestDictionary(t *testing.T) {
+	dictionarytest.Run(t, func(t *testing.T) types.Dictionary {
+		d, err := NewDictionary(filepath.Join(t.TempDir(), "lexicon.sqlite"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	})
+}
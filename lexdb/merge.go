@@ -0,0 +1,242 @@
+package lexdb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"lexicon/util"
+	"strings"
+)
+
+// Definition policies of Merge, which definition is kept for words that are in both databases.
+const (
+	KeepNewest = "newest" // The definition of the word that was updated last.
+	KeepOurs   = "ours"   // The definition of this database.
+	KeepTheirs = "theirs" // The definition of the other database.
+)
+
+// MergePolicies lists the definition policies of Merge.
+var MergePolicies = []string{KeepNewest, KeepOurs, KeepTheirs}
+
+// Conflict is a difference between the two versions of a word found by Merge and how it was settled.
+type Conflict struct {
+	Name       string `json:"name"`
+	Field      string `json:"field"`
+	Resolution string `json:"resolution"`
+}
+
+// MergeReport describes what Merge did.
+type MergeReport struct {
+	Added     []string   `json:"added"`     // Words that were only in the other database.
+	Updated   []string   `json:"updated"`   // Words of both databases that changed.
+	Unchanged int        `json:"unchanged"` // Words of both databases that didn't change.
+	Conflicts []Conflict `json:"conflicts"`
+	Wods      int64      `json:"wods"` // Words of the day added to the cache.
+}
+
+// Merge adds the words of the lexicon database in path to this one, in a single transaction. Words
+// that are in both keep the earliest creation date, every tag and line of notes, and the definition
+// chosen by policy, one of MergePolicies. An unusable definition never replaces a usable one. The
+// cached words of the day are merged too. With dryRun the transaction is rolled back, so the
+// report tells what would change.
+func (x *Lexicon) Merge(ctx context.Context, path, policy string, dryRun bool) (*MergeReport, error) {
+	if !util.Contains(MergePolicies, policy) {
+		return nil, fmt.Errorf("unknown definition policy %q", policy)
+	}
+	src, err := openReadOnly(path)
+	if err != nil {
+		return nil, err
+	}
+	err = verify(ctx, src, path)
+	var version int
+	if err == nil {
+		version, err = SchemaVersion(ctx, src)
+	}
+	src.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	// Attached databases belong to a connection, the whole merge runs on this one.
+	conn, err := x.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, `ATTACH DATABASE ? AS other`, path); err != nil {
+		return nil, fmt.Errorf("unable to attach %s: %s", path, err)
+	}
+	defer conn.ExecContext(context.Background(), `DETACH DATABASE other`)
+
+	theirs := `SELECT ` + columns + ` FROM other.lexicon ORDER BY name`
+	if version < 1 {
+		// Tags and notes were added in version 1.
+		theirs = `SELECT name, definition, source, createdAt, updatedAt, NULL, NULL FROM other.lexicon ORDER BY name`
+	}
+
+	var report *MergeReport
+	err = retry(ctx, func() error {
+		report = &MergeReport{}
+		tx, err := conn.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		err = merge(ctx, tx, theirs, policy, report)
+		if err == nil && version >= 2 {
+			// The cache of the words of the day was added in version 2.
+			report.Wods, err = mergeWods(ctx, tx)
+		}
+		if err != nil || dryRun {
+			_ = tx.Rollback()
+			return err
+		}
+		return tx.Commit()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// merge merges the rows returned by the query theirs into the lexicon table of the main database.
+func merge(ctx context.Context, tx *sql.Tx, theirs, policy string, report *MergeReport) error {
+	ours, err := readRows(ctx, tx, `SELECT `+columns+` FROM main.lexicon`)
+	if err != nil {
+		return err
+	}
+	others, err := readRows(ctx, tx, theirs)
+	if err != nil {
+		return fmt.Errorf("unable to read the other database: %s", err)
+	}
+
+	// Names are matched once normalized, the way the lexicon saves them.
+	saved := make(map[string]row, len(ours))
+	for _, r := range ours {
+		saved[strings.ToLower(strings.TrimSpace(r.name))] = r
+	}
+	for _, t := range others {
+		key := strings.ToLower(strings.TrimSpace(t.name))
+		if key == "" {
+			continue
+		}
+		t.name = key
+		o, ok := saved[key]
+		if !ok {
+			_, err := tx.ExecContext(ctx, `INSERT INTO main.lexicon(`+columns+`) values(?,?,?,?,?,?,?)`,
+				t.name, t.definition, t.source, t.createdAt, t.updatedAt, t.tags, t.notes)
+			if err != nil {
+				return fmt.Errorf("unable to add %q: %w", key, err)
+			}
+			report.Added = append(report.Added, key)
+			saved[key] = t
+			continue
+		}
+
+		merged, conflicts := mergeRow(o, t, policy)
+		report.Conflicts = append(report.Conflicts, conflicts...)
+		if merged == o {
+			report.Unchanged++
+			continue
+		}
+		_, err := tx.ExecContext(ctx, `UPDATE main.lexicon SET definition = ?, source = ?, createdAt = ?, updatedAt = ?,
+			tags = ?, notes = ? WHERE name = ?`,
+			merged.definition, merged.source, merged.createdAt, merged.updatedAt, merged.tags, merged.notes, o.name)
+		if err != nil {
+			return fmt.Errorf("unable to update %q: %w", key, err)
+		}
+		if !util.Contains(report.Updated, key) {
+			report.Updated = append(report.Updated, key)
+		}
+		saved[key] = merged
+	}
+	return nil
+}
+
+// mergeWods adds the words of the day cached by the other database that this one doesn't have.
+func mergeWods(ctx context.Context, tx *sql.Tx) (int64, error) {
+	res, err := tx.ExecContext(ctx, `INSERT OR IGNORE INTO main.wods SELECT source, date, word, fetchedAt FROM other.wods`)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// mergeRow merges theirs, a row of the other database, into ours and returns the result and the
+// differences that had to be settled.
+func mergeRow(ours, theirs row, policy string) (row, []Conflict) {
+	merged := ours
+	var conflicts []Conflict
+	settle := func(field, format string, args ...interface{}) {
+		conflicts = append(conflicts, Conflict{Name: ours.name, Field: field, Resolution: fmt.Sprintf(format, args...)})
+	}
+
+	if theirs.createdAt != ours.createdAt && theirs.createdAt != 0 {
+		if ours.createdAt == 0 || theirs.createdAt < ours.createdAt {
+			merged.createdAt = theirs.createdAt
+			settle("createdAt", "kept the earlier date of the other database, %s", formatUnix(theirs.createdAt))
+		} else {
+			settle("createdAt", "kept the earlier date of this database, %s", formatUnix(ours.createdAt))
+		}
+	}
+	if theirs.updatedAt > merged.updatedAt {
+		merged.updatedAt = theirs.updatedAt
+	}
+
+	if theirs.definition != ours.definition {
+		useTheirs, why := false, "as requested"
+		switch policy {
+		case KeepTheirs:
+			useTheirs = true
+		case KeepNewest:
+			useTheirs = theirs.updatedAt > ours.updatedAt
+			why = "updated last"
+		}
+		oursOK, theirsOK := checkDefinition(ours.definition) == nil, checkDefinition(theirs.definition) == nil
+		if useTheirs && !theirsOK && oursOK || !useTheirs && !oursOK && theirsOK {
+			useTheirs, why = !useTheirs, "the other one isn't usable"
+		}
+		if useTheirs {
+			merged.definition, merged.source = theirs.definition, theirs.source
+			settle("definition", "kept the definition of the other database, %s", why)
+		} else {
+			settle("definition", "kept the definition of this database, %s", why)
+		}
+	}
+
+	tags := splitTags(ours.tags)
+	var added []string
+	for _, t := range splitTags(theirs.tags) {
+		if !util.Contains(tags, t) {
+			tags = append(tags, t)
+			added = append(added, t)
+		}
+	}
+	if len(added) > 0 {
+		merged.tags = strings.Join(tags, ",")
+		settle("tags", "added %s from the other database", strings.Join(added, ", "))
+	}
+
+	lines := noteLines(ours.notes)
+	n := len(lines)
+	for _, l := range noteLines(theirs.notes) {
+		if !util.Contains(lines, l) {
+			lines = append(lines, l)
+		}
+	}
+	if len(lines) > n {
+		merged.notes = strings.Join(lines, "\n")
+		settle("notes", "added the notes of the other database (%d lines)", len(lines)-n)
+	}
+	return merged, conflicts
+}
+
+// noteLines returns the non-blank lines of notes.
+func noteLines(notes string) []string {
+	var lines []string
+	for _, l := range strings.Split(notes, "\n") {
+		if strings.TrimSpace(l) != "" {
+			lines = append(lines, l)
+		}
+	}
+	return lines
+}
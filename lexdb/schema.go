@@ -0,0 +1,108 @@
+package lexdb
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// schemaVersion is the version of the database schema this package works with. It's stored in the
+// user_version pragma of the database.
+const schemaVersion = 2
+
+// columns lists the columns of the lexicon table in the order readRecord scans them.
+const columns = `name, definition, source, createdAt, updatedAt, tags, notes`
+
+// migrations upgrade the schema one version at a time. migrations[i] upgrades a database from
+// version i to version i+1.
+var migrations = []func(tx *sql.Tx) error{
+	// Version 1 adds tags and notes. A new database has no lexicon table yet, which is created as
+	// it was in version 0.
+	func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`CREATE TABLE IF NOT EXISTS lexicon (
+			name       TEXT NOT NULL,
+			definition TEXT,
+			source     TEXT,
+			createdAt  INTEGER NOT NULL,
+			updatedAt  INTEGER NOT NULL,
+			PRIMARY KEY (name)
+		)`); err != nil {
+			return err
+		}
+		for _, column := range []string{"tags", "notes"} {
+			exists, err := hasColumn(tx, "lexicon", column)
+			if err != nil {
+				return err
+			}
+			if exists {
+				continue
+			}
+			if _, err := tx.Exec(fmt.Sprintf(`ALTER TABLE lexicon ADD COLUMN %s TEXT`, column)); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	// Version 2 adds the cache of the words of the day.
+	func(tx *sql.Tx) error {
+		_, err := tx.Exec(`CREATE TABLE IF NOT EXISTS wods (
+			source    TEXT NOT NULL,
+			date      TEXT NOT NULL,
+			word      TEXT NOT NULL,
+			fetchedAt INTEGER NOT NULL,
+			PRIMARY KEY (source, date)
+		)`)
+		return err
+	},
+}
+
+// migrate upgrades the database schema to schemaVersion.
+func migrate(db *sql.DB) error {
+	var version int
+	if err := db.QueryRow(`PRAGMA user_version`).Scan(&version); err != nil {
+		return fmt.Errorf("unable to read schema version: %s", err)
+	}
+	if version > schemaVersion {
+		return fmt.Errorf("database schema version %d is newer than the supported version %d", version, schemaVersion)
+	}
+
+	for ; version < schemaVersion; version++ {
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		if err := migrations[version](tx); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("unable to migrate schema to version %d: %s", version+1, err)
+		}
+		// PRAGMA statements don't support placeholders.
+		if _, err := tx.Exec(fmt.Sprintf(`PRAGMA user_version = %d`, version+1)); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func hasColumn(tx *sql.Tx, table, column string) (bool, error) {
+	rows, err := tx.Query(fmt.Sprintf(`PRAGMA table_info(%s)`, table))
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, typ string
+		var dflt sql.NullString
+		if err := rows.Scan(&cid, &name, &typ, &notNull, &dflt, &pk); err != nil {
+			return false, err
+		}
+		if name == column {
+			return true, nil
+		}
+	}
+	return false, rows.Err()
+}
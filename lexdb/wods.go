@@ -0,0 +1,58 @@
+package lexdb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"lexicon/types"
+	"log"
+	"time"
+)
+
+// FindWod returns the cached word of the day of source for date, or types.NotFound.
+func (x *Lexicon) FindWod(ctx context.Context, source, date string) (*types.Wod, error) {
+	var wod types.Wod
+	err := x.db.QueryRowContext(ctx, `SELECT date, word FROM wods WHERE source = ? AND date = ?`, source, date).
+		Scan(&wod.Date, &wod.Word)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, types.NotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &wod, nil
+}
+
+// SaveWod caches the word of the day of source, replacing the one of the same date if any.
+func (x *Lexicon) SaveWod(ctx context.Context, source string, wod *types.Wod) error {
+	err := retry(ctx, func() error {
+		_, err := x.db.ExecContext(ctx,
+			`INSERT OR REPLACE INTO wods(source, date, word, fetchedAt) VALUES(?,?,?,?)`,
+			source, wod.Date, wod.Word, time.Now().Unix())
+		return err
+	})
+	if err != nil {
+		log.Printf("Unable to insert record: %s", err)
+	}
+	return err
+}
+
+// ListWods returns the cached words of the day of source sorted by date.
+func (x *Lexicon) ListWods(ctx context.Context, source string) ([]*types.Wod, error) {
+	rows, err := x.db.QueryContext(ctx, `SELECT date, word FROM wods WHERE source = ? ORDER BY date`, source)
+	if err != nil {
+		log.Printf("Unable to query wods table: %s", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var wods []*types.Wod
+	for rows.Next() {
+		var wod types.Wod
+		if err := rows.Scan(&wod.Date, &wod.Word); err != nil {
+			return nil, err
+		}
+		wods = append(wods, &wod)
+	}
+	return wods, rows.Err()
+}
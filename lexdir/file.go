@@ -0,0 +1,317 @@
+package lexdir
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"lexicon/render"
+	"lexicon/types"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// fileExt is the extension of the files of the words.
+const fileExt = ".md"
+
+const (
+	frontMatterDelim = "---\n"
+	generatedNotice  = "<!-- Generated from the front matter, which is what the lexicon reads. Changes below this line are overwritten. -->\n"
+)
+
+// frontMatter is the YAML header of the file of a word. The definition is kept as a YAML tree
+// rather than a JSON string so that diffs are readable.
+type frontMatter struct {
+	Name       string    `yaml:"name"`
+	Source     string    `yaml:"source,omitempty"`
+	Created    time.Time `yaml:"created"`
+	Updated    time.Time `yaml:"updated"`
+	Tags       []string  `yaml:"tags,omitempty"`
+	Notes      string    `yaml:"notes,omitempty"`
+	Definition yaml.Node `yaml:"definition,omitempty"`
+}
+
+// encode returns the content of the file of a lexeme: the front matter followed by the word
+// rendered in Markdown for people browsing the directory.
+func encode(lexeme *types.Lexeme) ([]byte, error) {
+	fm := frontMatter{
+		Name:    lexeme.Name,
+		Source:  lexeme.Source,
+		Created: timestamp(lexeme.CreatedAt),
+		Updated: timestamp(lexeme.UpdatedAt),
+		Tags:    lexeme.Tags,
+		Notes:   lexeme.Notes,
+	}
+	def, err := definitionNode(lexeme.Definition)
+	if err != nil {
+		return nil, err
+	}
+	if def != nil {
+		fm.Definition = *def
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(frontMatterDelim)
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(fm); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	buf.WriteString(frontMatterDelim)
+	buf.WriteString(generatedNotice)
+
+	// Definitions that can't be parsed are still saved, only the body goes without them.
+	e, _ := render.NewEntry(lexeme, "", fm.Created.Local().Format("2006-01-02"), true)
+	buf.WriteString("\n")
+	if err := markdown.Render(&buf, e); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+var markdown, _ = render.New(render.Markdown)
+
+// decode parses the front matter of the file of a word. The body is ignored.
+func decode(content []byte) (*types.Lexeme, error) {
+	if !bytes.HasPrefix(content, []byte(frontMatterDelim)) {
+		return nil, errors.New("missing front matter")
+	}
+	content = content[len(frontMatterDelim):]
+	end := bytes.Index(content, []byte("\n"+frontMatterDelim))
+	if end < 0 {
+		return nil, errors.New("unterminated front matter")
+	}
+
+	var fm frontMatter
+	if err := yaml.Unmarshal(content[:end+1], &fm); err != nil {
+		return nil, fmt.Errorf("invalid front matter: %s", err)
+	}
+	if strings.TrimSpace(fm.Name) == "" {
+		return nil, errors.New("the front matter has no name")
+	}
+	def, err := definitionJSON(&fm.Definition)
+	if err != nil {
+		return nil, fmt.Errorf("invalid definition: %s", err)
+	}
+	created, updated := time.Unix(fm.Created.Unix(), 0), time.Unix(fm.Updated.Unix(), 0)
+	return &types.Lexeme{
+		Name:       fm.Name,
+		Definition: def,
+		Source:     fm.Source,
+		CreatedAt:  &created,
+		UpdatedAt:  &updated,
+		Tags:       fm.Tags,
+		Notes:      fm.Notes,
+	}, nil
+}
+
+// readFile reads and decodes the file of a word.
+func readFile(path string) (*types.Lexeme, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	lexeme, err := decode(content)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+	return lexeme, nil
+}
+
+// writeFile replaces the file in path atomically: readers see either the old or the new content.
+func writeFile(path string, content []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), tmpPrefix+"*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(content)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// timestamp returns t in UTC, to the second like the other backends, so that files don't change
+// with the time zone of whoever writes them.
+func timestamp(t *time.Time) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return time.Unix(t.Unix(), 0).UTC()
+}
+
+// slug returns the base name of the file of a word: its letters and digits in lowercase, with
+// dashes in between.
+func slug(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+	if b.Len() == 0 {
+		return "word"
+	}
+	return b.String()
+}
+
+// definitionNode converts a definition, a JSON document, to a YAML tree that keeps the order of the
+// keys. Definitions that aren't JSON objects or arrays are kept as a string. Empty definitions
+// return nil.
+func definitionNode(def string) (*yaml.Node, error) {
+	if strings.TrimSpace(def) == "" {
+		return nil, nil
+	}
+	if t := strings.TrimSpace(def); !json.Valid([]byte(def)) || (t[0] != '{' && t[0] != '[') {
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: def}, nil
+	}
+	dec := json.NewDecoder(strings.NewReader(def))
+	dec.UseNumber()
+	return jsonNode(dec)
+}
+
+func jsonNode(dec *json.Decoder) (*yaml.Node, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch t := tok.(type) {
+	case json.Delim:
+		node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		if t == '{' {
+			node.Kind, node.Tag = yaml.MappingNode, "!!map"
+		}
+		for dec.More() {
+			if node.Kind == yaml.MappingNode {
+				key, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key.(string)})
+			}
+			value, err := jsonNode(dec)
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, value)
+		}
+		// The closing delimiter.
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		return node, nil
+	case string:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: t}, nil
+	case json.Number:
+		tag := "!!int"
+		if strings.ContainsAny(t.String(), ".eE") {
+			tag = "!!float"
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: t.String()}, nil
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: fmt.Sprint(t)}, nil
+	}
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}, nil
+}
+
+// definitionJSON converts a YAML tree written by definitionNode back to the JSON definition.
+func definitionJSON(node *yaml.Node) (string, error) {
+	switch {
+	case node.Kind == 0:
+		return "", nil
+	case node.Kind == yaml.ScalarNode && node.ShortTag() == "!!str":
+		return node.Value, nil
+	}
+	var buf bytes.Buffer
+	if err := writeJSON(&buf, node); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// writeJSON writes a YAML tree as compact JSON, the way the definitions are serialized.
+func writeJSON(w io.Writer, node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		return writeJSON(w, node.Content[0])
+	case yaml.AliasNode:
+		return writeJSON(w, node.Alias)
+	case yaml.MappingNode, yaml.SequenceNode:
+		open, close := "[", "]"
+		if node.Kind == yaml.MappingNode {
+			open, close = "{", "}"
+		}
+		io.WriteString(w, open)
+		for i, n := range node.Content {
+			switch {
+			case node.Kind == yaml.MappingNode && i%2 == 1:
+				io.WriteString(w, ":")
+			case i > 0:
+				io.WriteString(w, ",")
+			}
+			if node.Kind == yaml.MappingNode && i%2 == 0 {
+				// Keys are strings in JSON, whatever YAML made of them.
+				n = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: n.Value}
+			}
+			if err := writeJSON(w, n); err != nil {
+				return err
+			}
+		}
+		_, err := io.WriteString(w, close)
+		return err
+	}
+
+	switch node.ShortTag() {
+	case "!!int", "!!float":
+		if json.Valid([]byte(node.Value)) {
+			// Keep numbers as they were written.
+			_, err := io.WriteString(w, node.Value)
+			return err
+		}
+	case "!!null":
+		_, err := io.WriteString(w, "null")
+		return err
+	case "!!str", "!!timestamp", "!!binary":
+		buf, err := json.Marshal(node.Value)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(buf)
+		return err
+	}
+	var v interface{}
+	if err := node.Decode(&v); err != nil {
+		return err
+	}
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(buf)
+	return err
+}
@@ -0,0 +1,148 @@
+package lexdir
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	indexFile = ".index.json"
+	// tmpPrefix starts the names of files being written.
+	tmpPrefix = ".tmp-"
+	// indexVersion changes when the index format does, older indexes are rebuilt.
+	indexVersion = 1
+)
+
+// index maps the names of the words to their files, with what listing and statistics need. It's
+// derived from the files: entries whose file changed since it was indexed are read again.
+type index struct {
+	Version int                    `json:"version"`
+	Words   map[string]*indexEntry `json:"words"`
+}
+
+type indexEntry struct {
+	File      string   `json:"file"`
+	CreatedAt int64    `json:"createdAt"`
+	UpdatedAt int64    `json:"updatedAt"`
+	Tags      []string `json:"tags,omitempty"`
+	// Modification time in nanoseconds and size of the file when it was indexed.
+	ModTime int64 `json:"modTime"`
+	Size    int64 `json:"size"`
+}
+
+// stat records the modification time and size of the file in path.
+func (e *indexEntry) stat(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	e.ModTime, e.Size = info.ModTime().UnixNano(), info.Size()
+	return nil
+}
+
+// names returns the names of the words, sorted.
+func (x *index) names() []string {
+	names := make([]string, 0, len(x.Words))
+	for name := range x.Words {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// loadIndex reads the index and brings it up to date with the files, which may have been added,
+// edited or deleted by hand or by git. A missing or unreadable index is rebuilt.
+func (d *Directory) loadIndex() error {
+	if buf, err := os.ReadFile(d.path(indexFile)); err == nil {
+		if err := json.Unmarshal(buf, &d.index); err != nil || d.index.Version != indexVersion {
+			d.index = index{}
+		}
+	}
+	byFile := make(map[string]string, len(d.index.Words))
+	for name, e := range d.index.Words {
+		byFile[e.File] = name
+	}
+
+	entries, err := os.ReadDir(d.dir)
+	if err != nil {
+		return err
+	}
+	// Files that didn't change since they were indexed go first, so that they keep their words if
+	// new files have the same ones.
+	words := make(map[string]*indexEntry, len(entries))
+	var pending []os.FileInfo
+	for _, entry := range entries {
+		file := entry.Name()
+		if !entry.Type().IsRegular() || filepath.Ext(file) != fileExt || strings.HasPrefix(file, ".") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		if name, ok := byFile[file]; ok {
+			if e := d.index.Words[name]; e.ModTime == info.ModTime().UnixNano() && e.Size == info.Size() {
+				words[name] = e
+				continue
+			}
+		}
+		pending = append(pending, info)
+	}
+
+	changed := false
+	for _, info := range pending {
+		lexeme, err := readFile(d.path(info.Name()))
+		if err != nil {
+			log.Printf("Skipping %s", err)
+			continue
+		}
+		if other, ok := words[lexeme.Name]; ok {
+			log.Printf("Skipping %s: %q is already in %s", d.path(info.Name()), lexeme.Name, other.File)
+			continue
+		}
+		words[lexeme.Name] = &indexEntry{
+			File:      info.Name(),
+			CreatedAt: lexeme.CreatedAt.Unix(),
+			UpdatedAt: lexeme.UpdatedAt.Unix(),
+			Tags:      lexeme.Tags,
+			ModTime:   info.ModTime().UnixNano(),
+			Size:      info.Size(),
+		}
+		changed = true
+	}
+
+	if len(words) != len(d.index.Words) || d.index.Version != indexVersion {
+		changed = true
+	}
+	d.index = index{Version: indexVersion, Words: words}
+	if changed {
+		return d.saveIndex()
+	}
+	return nil
+}
+
+func (d *Directory) saveIndex() error {
+	buf, err := json.Marshal(d.index)
+	if err != nil {
+		return err
+	}
+	if err := writeFile(d.path(indexFile), buf); err != nil {
+		log.Printf("Unable to write the index: %s", err)
+		return err
+	}
+	return nil
+}
+
+// writeGitignore keeps the index and the files being written out of version control, unless the
+// directory already has a .gitignore.
+func writeGitignore(dir string) error {
+	path := filepath.Join(dir, ".gitignore")
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		return err
+	}
+	return os.WriteFile(path, []byte(indexFile+"\n"+tmpPrefix+"*\n"), 0600)
+}
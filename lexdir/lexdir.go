@@ -0,0 +1,228 @@
+// lexdir implements a dictionary that keeps each word in its own Markdown file with a YAML front
+// matter, so that the lexicon can be kept in version control and read without the lexicon:
+//
+//	---
+//	name: ephemeral
+//	source: dictionaryapi.com
+//	created: 2024-01-01T10:00:00Z
+//	updated: 2024-01-01T10:00:00Z
+//	tags:
+//	  - greek
+//	definition:
+//	  entries:
+//	    - meta:
+//	        id: ephemeral
+//	...
+//	---
+//	## ephemeral
+//	...
+//
+// Only the front matter is read, the rest of the file is the word rendered in Markdown. An index
+// of the words is kept in .index.json, which is rebuilt from the files when they change.
+package lexdir
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"lexicon/types"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Directory is a dictionary stored in a directory, one file per word.
+type Directory struct {
+	dir string
+	// mu guards the index and the files. Files are replaced atomically, so other processes never
+	// see partial writes, but they may overwrite each other's changes.
+	mu    sync.Mutex
+	index index
+}
+
+// NewDictionary returns a new Dictionary backed by the files in dir, which is created if it
+// doesn't exist.
+func NewDictionary(dir string) (types.Dictionary, error) {
+	d, err := Open(dir)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Open opens the directory of a dictionary, creating it if it doesn't exist, and brings its index up
+// to date.
+func Open(dir string) (*Directory, error) {
+	if dir == "" {
+		return nil, errors.New("missing directory")
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("unable to create the directory: %s", err)
+	}
+	if err := writeGitignore(dir); err != nil {
+		return nil, err
+	}
+	d := &Directory{dir: dir}
+	if err := d.loadIndex(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Find returns the word called name or types.NotFound.
+func (d *Directory) Find(ctx context.Context, name string) (*types.Lexeme, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	e, ok := d.index.Words[name]
+	if !ok {
+		return nil, types.NotFound
+	}
+	return readFile(d.path(e.File))
+}
+
+// Save adds a new word. Returns types.AlreadyExists if it's already there.
+func (d *Directory) Save(ctx context.Context, lexeme *types.Lexeme) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.index.Words[lexeme.Name]; ok {
+		return types.AlreadyExists
+	}
+	timestamp := time.Now()
+	if lexeme.CreatedAt == nil {
+		lexeme.CreatedAt = &timestamp
+	}
+	if lexeme.UpdatedAt == nil {
+		lexeme.UpdatedAt = &timestamp
+	}
+	return d.write(lexeme)
+}
+
+// Update replaces an existing word and sets its updatedAt timestamp to the current time. Returns
+// types.NotFound if the word doesn't exist.
+func (d *Directory) Update(ctx context.Context, lexeme *types.Lexeme) error {
+	timestamp := time.Now()
+	lexeme.UpdatedAt = &timestamp
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.index.Words[lexeme.Name]; !ok {
+		return types.NotFound
+	}
+	return d.write(lexeme)
+}
+
+// Upsert saves the word if it doesn't exist yet, otherwise it replaces the existing one. Timestamps
+// set by the caller are written as is.
+func (d *Directory) Upsert(ctx context.Context, lexeme *types.Lexeme) error {
+	timestamp := time.Now()
+	if lexeme.UpdatedAt == nil {
+		lexeme.UpdatedAt = &timestamp
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.write(lexeme)
+}
+
+// write writes the file of a word and updates the index. Words without a creation date keep the
+// one of the existing file, new words are created now.
+func (d *Directory) write(lexeme *types.Lexeme) error {
+	created := time.Now()
+	e, ok := d.index.Words[lexeme.Name]
+	if ok {
+		created = time.Unix(e.CreatedAt, 0)
+	} else {
+		e = &indexEntry{File: d.newFile(lexeme.Name)}
+	}
+	if lexeme.CreatedAt == nil {
+		lexeme.CreatedAt = &created
+	}
+
+	content, err := encode(lexeme)
+	if err != nil {
+		return err
+	}
+	path := d.path(e.File)
+	if err := writeFile(path, content); err != nil {
+		log.Printf("Unable to write %s: %s", path, err)
+		return err
+	}
+	e.CreatedAt, e.UpdatedAt, e.Tags = lexeme.CreatedAt.Unix(), lexeme.UpdatedAt.Unix(), lexeme.Tags
+	if err := e.stat(path); err != nil {
+		return err
+	}
+	d.index.Words[lexeme.Name] = e
+	return d.saveIndex()
+}
+
+// newFile returns the name of a file for a new word, which no other word uses.
+func (d *Directory) newFile(name string) string {
+	used := make(map[string]bool, len(d.index.Words))
+	for _, e := range d.index.Words {
+		used[e.File] = true
+	}
+	base := slug(name)
+	file := base + fileExt
+	for i := 2; ; i++ {
+		if _, err := os.Lstat(d.path(file)); !used[file] && os.IsNotExist(err) {
+			return file
+		}
+		file = base + "-" + strconv.Itoa(i) + fileExt
+	}
+}
+
+// Remove deletes a word. Returns types.NotFound if it doesn't exist.
+func (d *Directory) Remove(ctx context.Context, name string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	e, ok := d.index.Words[name]
+	if !ok {
+		return types.NotFound
+	}
+	if err := os.Remove(d.path(e.File)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	delete(d.index.Words, name)
+	return d.saveIndex()
+}
+
+// List returns every word, sorted by name. Files that can't be read are skipped.
+func (d *Directory) List(ctx context.Context) ([]*types.Lexeme, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var all []*types.Lexeme
+	for _, name := range d.index.names() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		lexeme, err := readFile(d.path(d.index.Words[name].File))
+		if err != nil {
+			log.Printf("Unable to read %q: %s", name, err)
+			continue
+		}
+		all = append(all, lexeme)
+	}
+	return all, nil
+}
+
+// Stats returns the number of words and how many of them were added today, in the last 7 days and
+// this month, from the index.
+func (d *Directory) Stats(ctx context.Context) ([]types.Stat, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	created := make([]time.Time, 0, len(d.index.Words))
+	for _, e := range d.index.Words {
+		created = append(created, time.Unix(e.CreatedAt, 0))
+	}
+	return types.AddedStats(created, types.Location(ctx)), nil
+}
+
+// Close does nothing, every change is already written.
+func (d *Directory) Close() error {
+	return nil
+}
+
+func (d *Directory) path(file string) string {
+	return filepath.Join(d.dir, file)
+}
@@ -0,0 +1,17 @@
+package lexdir
+
+import (
+	"lexicon/types"
+	"lexicon/types/dictionarytest"
+	"testing"
+)
+
+func TestDictionary(t *testing.T) {
+	dictionarytest.Run(t, func(t *testing.T) types.Dictionary {
+		d, err := NewDictionary(t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	})
+}
@@ -0,0 +1,167 @@
+// lexmem implements a dictionary that keeps the words in memory, for tests and for tools that
+// don't need to keep them.
+package lexmem
+
+import (
+	"context"
+	"lexicon/types"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Memory is a dictionary held in memory. It's safe for concurrent use.
+type Memory struct {
+	mu    sync.Mutex
+	words map[string]*types.Lexeme
+}
+
+// New returns an empty dictionary.
+func New() *Memory {
+	return &Memory{words: make(map[string]*types.Lexeme)}
+}
+
+// Find returns a copy of the word called name or types.NotFound.
+func (m *Memory) Find(ctx context.Context, name string) (*types.Lexeme, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	lexeme, ok := m.words[name]
+	if !ok {
+		return nil, types.NotFound
+	}
+	return clone(lexeme), nil
+}
+
+// Save adds a new word. Returns types.AlreadyExists if it's already there.
+func (m *Memory) Save(ctx context.Context, lexeme *types.Lexeme) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.words[lexeme.Name]; ok {
+		return types.AlreadyExists
+	}
+	timestamp := time.Now()
+	if lexeme.CreatedAt == nil {
+		lexeme.CreatedAt = &timestamp
+	}
+	if lexeme.UpdatedAt == nil {
+		lexeme.UpdatedAt = &timestamp
+	}
+	m.words[lexeme.Name] = clone(lexeme)
+	return nil
+}
+
+// Update replaces an existing word and sets its updatedAt timestamp to the current time. Returns
+// types.NotFound if the word doesn't exist.
+func (m *Memory) Update(ctx context.Context, lexeme *types.Lexeme) error {
+	timestamp := time.Now()
+	lexeme.UpdatedAt = &timestamp
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.update(lexeme)
+}
+
+// Upsert saves the word if it doesn't exist yet, otherwise it replaces the existing one. Timestamps
+// set by the caller are kept.
+func (m *Memory) Upsert(ctx context.Context, lexeme *types.Lexeme) error {
+	timestamp := time.Now()
+	if lexeme.UpdatedAt == nil {
+		lexeme.UpdatedAt = &timestamp
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.words[lexeme.Name]; !ok {
+		if lexeme.CreatedAt == nil {
+			lexeme.CreatedAt = &timestamp
+		}
+		m.words[lexeme.Name] = clone(lexeme)
+		return nil
+	}
+	return m.update(lexeme)
+}
+
+// update replaces an existing word, keeping its creation date if lexeme doesn't have one.
+func (m *Memory) update(lexeme *types.Lexeme) error {
+	old, ok := m.words[lexeme.Name]
+	if !ok {
+		return types.NotFound
+	}
+	updated := clone(lexeme)
+	if updated.CreatedAt == nil {
+		updated.CreatedAt = old.CreatedAt
+	}
+	m.words[lexeme.Name] = updated
+	return nil
+}
+
+// Modify changes a saved word with fn and updates it, see types.Modifier.
+func (m *Memory) Modify(ctx context.Context, name string, fn func(lexeme *types.Lexeme) error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	lexeme, ok := m.words[name]
+	if !ok {
+		return types.NotFound
+	}
+	lexeme = clone(lexeme)
+	if err := fn(lexeme); err != nil {
+		return err
+	}
+	timestamp := time.Now()
+	lexeme.UpdatedAt = &timestamp
+	return m.update(lexeme)
+}
+
+// Remove deletes a word. Returns types.NotFound if it doesn't exist.
+func (m *Memory) Remove(ctx context.Context, name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.words[name]; !ok {
+		return types.NotFound
+	}
+	delete(m.words, name)
+	return nil
+}
+
+// List returns copies of every word, sorted by name.
+func (m *Memory) List(ctx context.Context) ([]*types.Lexeme, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	all := make([]*types.Lexeme, 0, len(m.words))
+	for _, lexeme := range m.words {
+		all = append(all, clone(lexeme))
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Name < all[j].Name })
+	return all, nil
+}
+
+// Stats returns the number of words and how many of them were added today, in the last 7 days and
+// this month.
+func (m *Memory) Stats(ctx context.Context) ([]types.Stat, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	created := make([]time.Time, 0, len(m.words))
+	for _, lexeme := range m.words {
+		created = append(created, *lexeme.CreatedAt)
+	}
+	return types.AddedStats(created, types.Location(ctx)), nil
+}
+
+// Close does nothing.
+func (m *Memory) Close() error {
+	return nil
+}
+
+// clone returns a copy of lexeme that doesn't share memory with it, with the timestamps to the
+// second like the other dictionaries.
+func clone(lexeme *types.Lexeme) *types.Lexeme {
+	c := *lexeme
+	c.Tags = append([]string(nil), lexeme.Tags...)
+	if t := lexeme.CreatedAt; t != nil {
+		created := time.Unix(t.Unix(), 0)
+		c.CreatedAt = &created
+	}
+	if t := lexeme.UpdatedAt; t != nil {
+		updated := time.Unix(t.Unix(), 0)
+		c.UpdatedAt = &updated
+	}
+	return &c
+}
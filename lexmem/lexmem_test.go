@@ -0,0 +1,13 @@
+package lexmem
+
+import (
+	"lexicon/types"
+	"lexicon/types/dictionarytest"
+	"testing"
+)
+
+func TestDictionary(t *testing.T) {
+	dictionarytest.Run(t, func(t *testing.T) types.Dictionary {
+		return New()
+	})
+}
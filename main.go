@@ -1,26 +1,22 @@
 package main
 
 import (
-	"bufio"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
+	"lexicon/config"
 	"lexicon/dictapi"
-	"lexicon/lexapi"
-	"lexicon/lexdb"
+	"lexicon/exporter"
+	"lexicon/render"
 	"lexicon/types"
 	"lexicon/util"
 	"log"
-	"math/rand"
-	"net/http"
-	"net/url"
 	"os"
+	"os/signal"
 	"strings"
 	"time"
 
-	"github.com/fatih/color"
-
 	_ "github.com/mattn/go-sqlite3"
 )
 
@@ -30,6 +26,7 @@ const dateTimeFormat = "2006-01-02 15:04:05"
 const (
 	newEntry      = 1
 	existingEntry = 2
+	updatedEntry  = 3
 )
 const (
 	DictionaryApi = "dictionaryapi.com"
@@ -42,441 +39,478 @@ const (
 	FullDef
 )
 
-const entrySeparator = "---"
-
-func getDefinition(name string, dictionary types.Dictionary) (*types.Lexeme, int, error) {
-	res, err := dictionary.Find(name)
+func getDefinition(ctx context.Context, app *app, name string) (*types.Lexeme, int, error) {
+	res, err := app.dictionary.Find(ctx, name)
 	if err != nil {
 		if !errors.Is(err, types.NotFound) {
 			return nil, 0, err
 		}
 
-		def, err := dictapi.Define(name)
-		if err != nil {
-			return nil, 0, err
-		}
-
-		if err := dictapi.Save(name); err != nil {
-			// Not a critical error, simply log a message
-			log.Printf("Unable to register word with Merriam-Webster: %s", err)
-		} else {
-			log.Printf("Saved %q on Merriam-Webster", name)
-		}
-
-		defstr, err := util.Serialize(def)
+		lex, err := fetchDefinition(ctx, name)
 		if err != nil {
-			log.Printf("Unable to serialize %v: %s", def, err)
 			return nil, 0, err
 		}
-		lex := types.Lexeme{
-			Name:       name,
-			Definition: string(defstr),
-			Source:     DictionaryApi,
-		}
 
-		return &lex, newEntry, err
+		saveOnMerriamWebster(ctx, app, name)
+		return lex, newEntry, nil
 	}
 	return res, existingEntry, nil
 }
 
-func defineName(name string, dictionary types.Dictionary) error {
-	def, status, err := getDefinition(name, dictionary)
-	if err != nil {
-		return err
+// saveOnMerriamWebster registers the word in the Merriam-Webster word list, unless the provider is
+// disabled in the profile.
+func saveOnMerriamWebster(ctx context.Context, app *app, name string) {
+	if !app.profile.HasProvider(config.MerriamWebster) {
+		return
 	}
-
-	if status == newEntry {
-		if err := dictionary.Save(def); err != nil {
-			log.Printf("Unable to save %v: %s", def, err)
-			return err
-		}
+	if err := dictapi.Save(ctx, name); err != nil {
+		// Not a critical error, simply log a message
+		log.Printf("Unable to register word with Merriam-Webster: %s", err)
+	} else {
+		log.Printf("Saved %q on Merriam-Webster", name)
 	}
-
-	printLexeme(def, status, ShortDef)
-	return nil
 }
 
-func formatCognates(cognates []types.Cognate) string {
-	var res string
-
-	for i, cognate := range cognates {
-		res += cognate.Label + " " + strings.Join(util.QuoteStrings(cognate.Targets), ",")
-		if i+1 < len(cognates) {
-			res += " | "
-		}
+// removeFromMerriamWebster removes the word from the Merriam-Webster word list, unless the provider
+// is disabled in the profile.
+func removeFromMerriamWebster(ctx context.Context, app *app, name string) {
+	if !app.profile.HasProvider(config.MerriamWebster) {
+		return
+	}
+	if err := dictapi.Remove(ctx, name); err != nil {
+		log.Printf("Unable to remove the word from Merriam-Webster: %s", err)
 	}
-	return res
 }
 
-func getPronunciations(headword types.Headword) string {
-	if len(headword.Pronunciations) == 0 {
-		return ""
+// fetchDefinition queries dictionaryapi.com and returns a lexeme with the serialized definition.
+func fetchDefinition(ctx context.Context, name string) (*types.Lexeme, error) {
+	def, err := dictapi.Define(ctx, name)
+	if err != nil {
+		return nil, err
 	}
-	var prons []string
-	for _, p := range headword.Pronunciations {
-		prons = append(prons, p.Text)
+
+	defstr, err := util.Serialize(def)
+	if err != nil {
+		log.Printf("Unable to serialize %v: %s", def, err)
+		return nil, err
 	}
-	return fmt.Sprintf("    (%s)", strings.Join(prons, ","))
+	return &types.Lexeme{
+		Name:       name,
+		Definition: string(defstr),
+		Source:     DictionaryApi,
+	}, nil
 }
 
-func formatLocalDateTime(t *time.Time) string {
-	loc, err := time.LoadLocation("America/Los_Angeles")
+// lookup returns the lexeme for name. New words are defined and saved in the dictionary.
+func lookup(ctx context.Context, app *app, name string) (*types.Lexeme, int, error) {
+	def, status, err := getDefinition(ctx, app, name)
 	if err != nil {
-		log.Printf("Unable to load location: %s", err)
-		return t.Format(time.DateTime)
+		return nil, 0, err
 	}
-	return t.In(loc).Format(time.DateTime)
 
+	if status == newEntry {
+		if err := app.dictionary.Save(ctx, def); err != nil {
+			log.Printf("Unable to save %v: %s", def, err)
+			return nil, 0, err
+		}
+	}
+	return def, status, nil
 }
 
-func printLexeme(lexeme *types.Lexeme, nameStatus int, printMode PrintMode) {
-	var out = new(strings.Builder)
-	label := labelName(nameStatus)
-
-	title := color.New(color.FgGreen, color.Bold)
-	_, _ = fmt.Fprintf(out, "%s", title.Sprintf("\n%s", lexeme.Name))
-	_, _ = fmt.Fprintf(out, "\t%s", color.RedString(label))
-
-	_, _ = fmt.Fprintf(out, "%s", title.Sprintf("\n%s\n", strings.Repeat("=", len(lexeme.Name))))
-
-	_, _ = fmt.Fprintf(out, "Added on %s\n", formatLocalDateTime(lexeme.CreatedAt))
-
-	var lex types.Definition
-	if err := json.Unmarshal([]byte(lexeme.Definition), &lex); err != nil {
-		log.Printf("Unable to parse definition: %s -> %s", err, lexeme.Definition)
-		return
+func defineName(ctx context.Context, app *app, name string, mode PrintMode) error {
+	def, status, err := lookup(ctx, app, name)
+	if err != nil {
+		return err
 	}
 
-	subtitle := color.New(color.FgBlue)
-	for i, e := range lex.Entries {
-		_, _ = fmt.Fprintf(out, "\n")
-
-		gf := e.GrammaticalFunction
-		if len(gf) > 0 {
-			hw := e.Headword.Text
-			prons := getPronunciations(e.Headword)
-			_, _ = fmt.Fprintf(out, "%s\n", subtitle.Sprintf("%s — %s%s", hw, gf, prons))
-
-			for _, sd := range e.ShortDefinitions {
-				_, _ = fmt.Fprintf(out, "• %s\n", sd)
-			}
-		} else if len(e.Cognates) > 0 {
-			cognates := formatCognates(e.Cognates)
-			_, _ = fmt.Fprintf(out, "%s\n", subtitle.Sprintf("%s — %s", e.Headword.Text, cognates))
-		}
-		if printMode == FullDef {
-			for _, d := range e.Defs {
-				printDefinition(out, d)
-			}
-
-			if len(e.Quotes) > 0 {
-				_, _ = fmt.Fprintf(out, "\n%s\n", color.BlueString("Quotes"))
-				for _, q := range e.Quotes {
-					printQuote(out, q)
-				}
-			}
+	app.last = def
+	return newFormatter(ctx, app).lexeme(def, status, mode)
+}
 
-			if i+1 < len(lex.Entries) {
-				_, _ = fmt.Fprintf(out, "%s\n", strings.Repeat("—", 80))
-			}
-		}
-		_, _ = fmt.Fprintf(out, "%s\n", entrySeparator)
+// formatTime formats t in the display time zone with the date format of the profile.
+func (a *app) formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
 	}
+	return t.In(a.location).Format(a.profile.DateFormat)
+}
 
-	fmt.Print(abridgeOutput(out))
+// today returns the start of the current day in loc.
+func today(loc *time.Location) time.Time {
+	y, m, d := time.Now().In(loc).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, loc)
 }
 
-// abridgeOutput shortens the output so that it can fit nicely on a screen w/o scrolling.
-func abridgeOutput(builder *strings.Builder) string {
-	lines := strings.Split(builder.String(), "\n")
-	var out strings.Builder
-	for i, line := range lines {
-		if line == entrySeparator {
-			// Arbitrary number
-			// TODO: Make this value configurable.
-			if i > 25 {
-				break
-			}
-			continue
-		}
-		_, _ = fmt.Fprintf(&out, "%s\n", line)
+// parseDate parses a YYYY-MM-DD date as the start of that day in loc.
+func parseDate(s string, loc *time.Location) (time.Time, error) {
+	d, err := time.ParseInLocation(dateFormat, s, loc)
+	if err != nil {
+		return time.Time{}, usageError{fmt.Sprintf("invalid date %q, expected YYYY-MM-DD", s)}
 	}
-	return out.String()
+	return d, nil
 }
 
-func printDefinition(out *strings.Builder, d types.Def) {
-	_, _ = fmt.Fprintf(out, "\n")
-	if len(d.VerbDivider) > 0 {
-		_, _ = fmt.Fprintf(out, "%s\n", d.VerbDivider)
+// printLexeme renders a lexeme with the renderer of the profile. Short output is cut to fit the
+// screen and full output goes through the pager.
+func printLexeme(ctx context.Context, app *app, lexeme *types.Lexeme, nameStatus int, mode PrintMode) error {
+	r, err := newRenderer(&app.profile)
+	if err != nil {
+		return err
+	}
+	e := renderEntry(app, lexeme, nameStatus, mode)
+	if !e.Full {
+		return printEntries(app, r, e)
 	}
-	for _, s := range d.Senses {
-		_, _ = fmt.Fprintf(out, "%s\n", s.Text)
 
-		if len(s.UsageNotes) > 0 {
-			for _, u := range s.UsageNotes {
-				_, _ = fmt.Fprintf(out, "  •%q\n", u)
-			}
-		}
-		if len(s.VerbalIllustrations) > 0 {
-			for _, i := range s.VerbalIllustrations {
-				_, _ = fmt.Fprintf(out, "  • %q\n", i)
-			}
-			_, _ = fmt.Fprintf(out, "\n")
-		}
+	app.pending = nil
+	var out strings.Builder
+	if err := r.Render(&out, e); err != nil {
+		return err
 	}
+	return page(ctx, app, out.String())
 }
 
-func printQuote(out *strings.Builder, q types.Quote) {
-	_, _ = fmt.Fprintf(out, "  %q\n", q.Text)
-	_, _ = fmt.Fprintf(out, "  %s, %s, %s\n\n", q.Source, q.Author, q.PublicationDate)
+// newRenderer returns the template of the profile if set, otherwise its built-in renderer.
+func newRenderer(profile *config.Profile) (render.Renderer, error) {
+	if profile.Template != "" {
+		return render.ParseTemplateFile(profile.Template)
+	}
+	return render.New(profile.Renderer)
+}
+
+// renderEntry returns the entry of a lexeme with the settings of the profile.
+func renderEntry(app *app, lexeme *types.Lexeme, nameStatus int, printMode PrintMode) render.Entry {
+	added := app.formatTime(lexeme.CreatedAt)
+	e, err := render.NewEntry(lexeme, strings.TrimSpace(labelName(nameStatus)), added, printMode == FullDef)
+	if err != nil {
+		log.Printf("Unable to parse definition: %s -> %s", err, lexeme.Definition)
+	}
+	return e
 }
 
 func labelName(nameStatus int) string {
-	if nameStatus == newEntry {
+	switch nameStatus {
+	case newEntry:
 		return "\tNew"
+	case updatedEntry:
+		return "\tUpdated"
 	}
 	return ""
 }
 
-// interactive launches an interactive session where the user can define as many words as needed.
-func interactive(dictionary types.Dictionary) {
-	scanner := bufio.NewReader(os.Stdin)
-	for {
-		fmt.Printf("\n> ")
-		line, err := scanner.ReadString('\n')
-		if err != nil {
-			if err == io.EOF {
-				break
-			}
-			log.Printf("scanner error: %s", err)
-			continue
-		}
+func define(ctx context.Context, app *app, name string) error {
+	return defineName(ctx, app, normalizeName(name), app.mode())
+}
 
-		input := strings.ToLower(strings.TrimSpace(line))
-		if strings.TrimSpace(input) == "!exit" {
-			break
-		}
+// normalizeName returns name the way words are saved: trimmed and lowercase, so that "Apple" and
+// "apple" are the same word.
+func normalizeName(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
 
-		// Skip empty lines
-		if len(input) == 0 {
-			continue
-		}
+// refresh fetches the definition of a saved word again and updates it in place.
+func refresh(ctx context.Context, app *app, name string) error {
+	name = normalizeName(name)
+	dictionary := app.dictionary
+	lexeme, err := dictionary.Find(ctx, name)
+	if err != nil {
+		return err
+	}
 
-		if err := defineName(input, dictionary); err != nil {
-			log.Printf("Unable to define %q: %s", input, err)
-		}
+	fresh, err := fetchDefinition(ctx, name)
+	if err != nil {
+		return err
 	}
-}
+	lexeme.Definition = fresh.Definition
+	lexeme.Source = fresh.Source
 
-func define(dictionary types.Dictionary) error {
-	if len(os.Args) < 3 {
-		return errors.New("you must provide a name")
+	// Without createdAt Update keeps the stored one, whatever Find returned.
+	createdAt := lexeme.CreatedAt
+	lexeme.CreatedAt = nil
+	err = dictionary.Update(ctx, lexeme)
+	lexeme.CreatedAt = createdAt
+	if err != nil {
+		log.Printf("Unable to update %q: %s", name, err)
+		return err
 	}
-	return defineName(os.Args[2], dictionary)
+
+	return newFormatter(ctx, app).lexeme(lexeme, updatedEntry, app.mode())
 }
 
-// defineBatch reads words from a file and defines all words in it. If the words contain a timestamp
-// the createdAt and updatedAt timestamps are set to such timestamp. This command is useful for
-// importing words from other sources while still keeping the original dates.
-func defineBatch(dictionary types.Dictionary) error {
-	if len(os.Args) < 3 {
-		return errors.New("missing file name")
-	}
-	f, err := os.Open(os.Args[2])
+func stats(ctx context.Context, app *app) error {
+	stats, err := app.dictionary.Stats(ctx)
 	if err != nil {
 		return err
 	}
-	buf, err := io.ReadAll(f)
+
+	return newFormatter(ctx, app).stats(stats)
+}
+
+// list prints the words of the lexicon sorted by name, optionally only those with a tag.
+func list(ctx context.Context, app *app, tag string) error {
+	lexemes, err := app.dictionary.List(ctx)
 	if err != nil {
 		return err
 	}
+	return newFormatter(ctx, app).lexemes(exporter.Filter{Tag: tag}.Apply(lexemes))
+}
+
+// exportOptions are the flags of the export command.
+type exportOptions struct {
+	format string // Output format.
+	output string // Output file, the standard output by default.
+	from   string // Only words added on or after this date.
+	to     string // Only words added on or before this date.
+	tag    string // Only words with this tag.
+}
 
-	var failed []string
-	lines := strings.Split(strings.TrimSpace(string(buf)), "\n")
-	for _, line := range lines {
-		tokens := strings.Split(line, ",")
-		name := strings.ToLower(tokens[0])
-		def, nameStatus, err := getDefinition(name, dictionary)
+// export writes the lexemes that match the filters to a file or the standard output. The dates of
+// the filters are days in loc.
+func export(ctx context.Context, dictionary types.Dictionary, loc *time.Location, opts exportOptions) error {
+	filter := exporter.Filter{Tag: opts.tag}
+	if opts.from != "" {
+		d, err := parseDate(opts.from, loc)
 		if err != nil {
-			log.Printf("Unable to define %q: %s", name, err)
-			failed = append(failed, line)
-			continue
+			return err
 		}
-
-		// The word comes with a timestamp, we'll update the timestamps accordingly.
-		if len(tokens) == 2 {
-			timestamp, err := time.Parse(dateTimeFormat, tokens[1])
-			if err != nil {
-				log.Printf("Unable to parse timestamp: %s", err)
-				return err
-			}
-			def.UpdatedAt = &timestamp
-			def.CreatedAt = &timestamp
+		filter.From = d
+	}
+	if opts.to != "" {
+		d, err := parseDate(opts.to, loc)
+		if err != nil {
+			return err
 		}
+		filter.To = d.AddDate(0, 0, 1)
+	}
 
-		if err := dictionary.Save(def); err != nil {
-			log.Printf("Unable to save %q: %s", name, err)
-			failed = append(failed, line)
-			continue
+	lexemes, err := dictionary.List(ctx)
+	if err != nil {
+		return err
+	}
+	lexemes = filter.Apply(lexemes)
+	// Dates are shown in the display time zone.
+	for _, lex := range lexemes {
+		for _, t := range []**time.Time{&lex.CreatedAt, &lex.UpdatedAt} {
+			if *t != nil {
+				local := (*t).In(loc)
+				*t = &local
+			}
 		}
+	}
 
-		if nameStatus == newEntry {
-			// Wait to avoid throttling.
-			waitTime := time.Millisecond * time.Duration(100+rand.Intn(2000))
-			time.Sleep(waitTime)
+	if opts.output == "" && opts.format == exporter.Anki {
+		opts.output = "lexicon.apkg"
+	}
+	out := os.Stdout
+	if opts.output != "" {
+		f, err := os.Create(opts.output)
+		if err != nil {
+			return err
 		}
+		defer f.Close()
+		out = f
 	}
 
-	log.Println()
-	log.Printf("Successful definitions: %d", len(lines)-len(failed))
-	log.Printf("Failed definitions: %d", len(failed))
-	for _, f := range failed {
-		log.Printf("• %s", f)
+	if err := exporter.Export(opts.format, out, lexemes); err != nil {
+		return err
+	}
+	if opts.output != "" {
+		log.Printf("Exported %d words to %s", len(lexemes), opts.output)
 	}
 	return nil
 }
 
-func getWod(date string) (*types.Wod, error) {
-	u := fmt.Sprintf("https://rafaelrendon.io/wod/%s", url.PathEscape(date))
-	res, err := http.Get(u)
+// search prints the saved words whose name, short definitions, tags or notes contain text, ignoring
+// case.
+func search(ctx context.Context, app *app, text string) error {
+	lexemes, err := app.dictionary.List(ctx)
 	if err != nil {
-		log.Printf("HTTP call to %s failed with error: %s", u, err)
-		return nil, err
+		return err
 	}
-	defer res.Body.Close()
-
-	if res.StatusCode == http.StatusNotFound {
-		return nil, fmt.Errorf("word of the day for '%s' not found", date)
+	text = strings.ToLower(text)
+	var matches []*types.Lexeme
+	for _, lex := range lexemes {
+		if matchesText(lex, text) {
+			matches = append(matches, lex)
+		}
 	}
+	return newFormatter(ctx, app).lexemes(exporter.Filter{}.Apply(matches))
+}
 
-	buf, err := io.ReadAll(res.Body)
-	if err != nil {
-		log.Printf("Unable to read response's body: %s", err)
-		return nil, err
+func matchesText(lex *types.Lexeme, text string) bool {
+	fields := []string{lex.Name, lex.Notes}
+	fields = append(fields, lex.Tags...)
+	var def types.Definition
+	if err := json.Unmarshal([]byte(lex.Definition), &def); err == nil {
+		for _, e := range def.Entries {
+			fields = append(fields, e.ShortDefinitions...)
+		}
 	}
-
-	var wod types.Wod
-	if err := json.Unmarshal(buf, &wod); err != nil {
-		log.Printf("Unable to unmarshal response's body: %s", err)
-		return nil, err
+	for _, f := range fields {
+		if strings.Contains(strings.ToLower(f), text) {
+			return true
+		}
 	}
-
-	return &wod, nil
+	return false
 }
 
-func printWod(date string) error {
-	res, err := getWod(date)
+// tag adds tags to a saved word, or removes them.
+func tag(ctx context.Context, app *app, name string, tags []string, remove bool) error {
+	name = normalizeName(name)
+	lexeme, err := modify(ctx, app.dictionary, name, func(lexeme *types.Lexeme) error {
+		for _, t := range tags {
+			switch {
+			case remove:
+				var kept []string
+				for _, e := range lexeme.Tags {
+					if e != t {
+						kept = append(kept, e)
+					}
+				}
+				lexeme.Tags = kept
+			case !util.Contains(lexeme.Tags, t):
+				lexeme.Tags = append(lexeme.Tags, t)
+			}
+		}
+		return nil
+	})
 	if err != nil {
 		return err
 	}
-
-	fmt.Printf("%s %s\n", res.Date, res.Word)
+	log.Printf("Tags of %q: %s", name, strings.Join(lexeme.Tags, ", "))
 	return nil
 }
 
-func wod() error {
-	// Default date range (today's date)
-	startDate := time.Now()
-	endDate := startDate
-
-	// Parse one or two dates
-	var dates []time.Time
-	for i := 2; i < 4 && i < len(os.Args); i++ {
-		d, err := time.Parse(dateFormat, os.Args[i])
-		if err != nil {
-			return err
-		}
-		dates = append(dates, d)
-	}
-
-	if len(dates) > 0 {
-		startDate = dates[0]
-		endDate = dates[len(dates)-1]
-	}
-
-	for d := startDate; d.Unix() <= endDate.Unix(); d = d.Add(time.Hour * 24) {
-		if err := printWod(d.Format(time.DateOnly)); err != nil {
-			return err
+// note appends a line to the notes of a saved word.
+func note(ctx context.Context, app *app, name, text string) error {
+	name = normalizeName(name)
+	_, err := modify(ctx, app.dictionary, name, func(lexeme *types.Lexeme) error {
+		if lexeme.Notes != "" {
+			lexeme.Notes += "\n"
 		}
+		lexeme.Notes += text
+		return nil
+	})
+	if err != nil {
+		return err
 	}
+	log.Printf("Added a note to %q", name)
 	return nil
 }
 
-func stats(dictionary types.Dictionary) error {
-	stats, err := dictionary.Stats()
-	if err != nil {
-		return err
+// modify changes a saved word with fn and updates it, atomically if the dictionary supports it,
+// and returns the updated word.
+func modify(ctx context.Context, dictionary types.Dictionary, name string, fn func(*types.Lexeme) error) (*types.Lexeme, error) {
+	var lexeme *types.Lexeme
+	apply := func(l *types.Lexeme) error {
+		lexeme = l
+		return fn(l)
+	}
+	var err error
+	if m, ok := dictionary.(types.Modifier); ok {
+		err = m.Modify(ctx, name, apply)
+	} else if lexeme, err = dictionary.Find(ctx, name); err == nil {
+		if err = fn(lexeme); err == nil {
+			err = dictionary.Update(ctx, lexeme)
+		}
 	}
-
-	log.Printf("== Stats ==")
-	for _, stat := range stats {
-		log.Printf("%s: %v", stat.Name, stat.Value)
+	if err != nil && err != types.NotFound {
+		log.Printf("Unable to update %q: %s", name, err)
 	}
-	return nil
+	return lexeme, err
 }
 
-func remove(dictionary types.Dictionary) error {
-	if len(os.Args) < 3 {
-		return errors.New("you must provide a name")
-	}
-	name := os.Args[2]
-	if err := dictionary.Remove(name); err != nil {
+func remove(ctx context.Context, app *app, name string) error {
+	name = normalizeName(name)
+	if err := app.dictionary.Remove(ctx, name); err != nil {
 		log.Printf("Unable to remove %q: %s", name, err)
 		return err
 	}
 	log.Printf("Removed %q", name)
+	removeFromMerriamWebster(ctx, app, name)
 	return nil
 }
 
-func main() {
-	log.SetFlags(0)
-	log.SetOutput(os.Stdout)
-
-	var dictionary types.Dictionary
+// configSettings implements the config command. get prints the effective value of a setting, set
+// writes a setting to the selected profile of the configuration file and show prints all of them.
+func configSettings(app *app, args []string) error {
+	if app.cfg == nil {
+		return fmt.Errorf("unable to read the configuration file %s, fix it manually", app.configPath)
+	}
 
-	// TODO: read config from toml file.
-	if os.Getenv("DATA_SOURCE_TYPE") == "API" {
-		ac, err := lexapi.NewDictionary()
+	switch args[0] {
+	case "get":
+		if len(args) != 2 {
+			return usageError{"get expects a key"}
+		}
+		profile, err := app.cfg.Resolve(app.profileName)
 		if err != nil {
-			log.Fatalf("Failed to set up API client: %s", err)
+			return err
 		}
-		dictionary = ac
-	} else {
-		d, err := lexdb.NewDictionary()
+		v, err := profile.Get(args[1])
 		if err != nil {
-			log.Fatalf("Failed to set up database: %s", err)
+			return err
 		}
-		dictionary = d
-	}
-	defer dictionary.Close()
-
-	if len(os.Args) <= 1 {
-		// Launch the lexicon in interactive mode
-		interactive(dictionary)
-		return
-	}
-
-	command := os.Args[1]
-	switch command {
-	case "define":
-		if err := define(dictionary); err != nil {
-			log.Fatalf("define failed with error: %q", err)
+		fmt.Println(v)
+	case "set":
+		if len(args) != 3 {
+			return usageError{"set expects a key and a value"}
+		}
+		p, ok := app.cfg.Profiles[app.profileName]
+		if !ok {
+			p = &config.Profile{}
+		}
+		if err := p.Set(args[1], args[2]); err != nil {
+			return err
+		}
+		effective := config.Defaults()
+		effective.Merge(*p)
+		if err := effective.Validate(); err != nil {
+			return err
+		}
+		if app.cfg.Profiles == nil {
+			app.cfg.Profiles = make(map[string]*config.Profile)
 		}
-	case "define-batch":
-		if err := defineBatch(dictionary); err != nil {
-			log.Fatalf("define-batch failed with error: %q", err)
+		app.cfg.Profiles[app.profileName] = p
+		if err := app.cfg.Save(app.configPath); err != nil {
+			return err
 		}
-	case "wod":
-		if err := wod(); err != nil {
-			log.Fatalf("wod failed with error: %q", err)
+		if env := config.EnvVar(args[1]); env != "" && os.Getenv(env) != "" {
+			log.Printf("Note: %s is set and overrides this setting", env)
 		}
-	case "stats":
-		if err := stats(dictionary); err != nil {
-			log.Fatalf("stats failed with error: %q", err)
+	case "show":
+		if len(args) != 1 {
+			return usageError{"show expects no arguments"}
 		}
-	case "rm":
-		if err := remove(dictionary); err != nil {
-			log.Fatalf("rm failed with error: %q", err)
+		fmt.Printf("# %s, profile %q\n", app.configPath, app.profileName)
+		profile, err := app.cfg.Resolve(app.profileName)
+		if err != nil {
+			fmt.Printf("# error: %s\n", err)
 		}
+		fmt.Print(profile.Show())
+	default:
+		return usageError{fmt.Sprintf("unknown action %q, expected get, set or show", args[0])}
 	}
+	return nil
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetOutput(os.Stdout)
+
+	a := &app{}
+	a.commands = newCommands()
+	os.Exit(a.run(context.Background(), os.Args[1:]))
+}
+
+// notifyContext returns a context that is cancelled by the first of the signals, e.g. to cancel
+// in-flight work on Ctrl-C. The signals are then handled as usual again, so that a second one
+// terminates the program even if the work hangs. stop must be called to release the resources.
+func notifyContext(parent context.Context, signals ...os.Signal) (ctx context.Context, stop context.CancelFunc) {
+	ctx, stop = signal.NotifyContext(parent, signals...)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	return ctx, stop
 }
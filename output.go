@@ -0,0 +1,303 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"lexicon/exporter"
+	"lexicon/render"
+	"lexicon/types"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Output formats selected with the global --format flag.
+const (
+	formatText     = "text"
+	formatJSON     = "json"
+	formatJSONL    = "jsonl"
+	formatYAML     = "yaml"
+	formatMarkdown = "markdown"
+)
+
+var outputFormats = []string{formatText, formatJSON, formatJSONL, formatYAML, formatMarkdown}
+
+func isOutputFormat(format string) bool {
+	for _, f := range outputFormats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// formatter renders the results of the commands in one of the output formats. Only the text format
+// is meant for humans, the others are stable and free of colors so they can be used in scripts. The
+// print mode only changes the text and Markdown output, the others always hold the whole definition.
+type formatter interface {
+	lexeme(lexeme *types.Lexeme, nameStatus int, mode PrintMode) error
+	lexemes(lexemes []*types.Lexeme) error
+	stats(stats []types.Stat) error
+	wods(wods []*types.Wod) error
+}
+
+// newFormatter returns the formatter of the output format of the profile. Results are written to
+// the standard output.
+func newFormatter(ctx context.Context, app *app) formatter {
+	switch app.profile.Format {
+	case formatJSON:
+		return jsonFormatter{w: os.Stdout}
+	case formatJSONL:
+		return jsonFormatter{w: os.Stdout, lines: true}
+	case formatYAML:
+		return yamlFormatter{w: os.Stdout}
+	case formatMarkdown:
+		return markdownFormatter{w: os.Stdout, app: app}
+	}
+	return textFormatter{ctx: ctx, w: os.Stdout, app: app}
+}
+
+// textFormatter writes the human readable output of the lexicon.
+type textFormatter struct {
+	ctx context.Context
+	w   io.Writer
+	app *app
+}
+
+func (f textFormatter) lexeme(lexeme *types.Lexeme, nameStatus int, mode PrintMode) error {
+	return printLexeme(f.ctx, f.app, lexeme, nameStatus, mode)
+}
+
+func (f textFormatter) lexemes(lexemes []*types.Lexeme) error {
+	for _, lex := range lexemes {
+		line := fmt.Sprintf("%s\t%s", lex.Name, f.app.formatTime(lex.CreatedAt))
+		if len(lex.Tags) > 0 {
+			line += "\t" + strings.Join(lex.Tags, ", ")
+		}
+		if _, err := fmt.Fprintln(f.w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f textFormatter) stats(stats []types.Stat) error {
+	_, _ = fmt.Fprintf(f.w, "== Stats ==\n")
+	for _, stat := range stats {
+		if _, err := fmt.Fprintf(f.w, "%s: %v\n", stat.Name, stat.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f textFormatter) wods(wods []*types.Wod) error {
+	for _, wod := range wods {
+		if _, err := fmt.Fprintf(f.w, "%s %s\n", wod.Date, wod.Word); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// jsonFormatter writes indented JSON documents or, if lines is set, one compact JSON value per line
+// with lists written one element per line.
+type jsonFormatter struct {
+	w     io.Writer
+	lines bool
+}
+
+func (f jsonFormatter) encode(v interface{}) error {
+	enc := json.NewEncoder(f.w)
+	if !f.lines {
+		enc.SetIndent("", "  ")
+	}
+	return enc.Encode(v)
+}
+
+// encodeList writes a list as a JSON array, or one element per line.
+func (f jsonFormatter) encodeList(values []interface{}) error {
+	if !f.lines {
+		if values == nil {
+			values = []interface{}{}
+		}
+		return f.encode(values)
+	}
+	for _, v := range values {
+		if err := f.encode(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f jsonFormatter) lexeme(lexeme *types.Lexeme, nameStatus int, mode PrintMode) error {
+	return f.encode(newLexemeOutput(lexeme, nameStatus))
+}
+
+func (f jsonFormatter) lexemes(lexemes []*types.Lexeme) error {
+	var values []interface{}
+	for _, lex := range lexemes {
+		values = append(values, newLexemeOutput(lex, existingEntry))
+	}
+	return f.encodeList(values)
+}
+
+func (f jsonFormatter) stats(stats []types.Stat) error {
+	var values []interface{}
+	for _, stat := range stats {
+		values = append(values, stat)
+	}
+	return f.encodeList(values)
+}
+
+func (f jsonFormatter) wods(wods []*types.Wod) error {
+	var values []interface{}
+	for _, wod := range wods {
+		values = append(values, wod)
+	}
+	return f.encodeList(values)
+}
+
+// yamlFormatter writes YAML documents. Values are converted through JSON so that the field names
+// and their order match the JSON output.
+type yamlFormatter struct {
+	w io.Writer
+}
+
+func (f yamlFormatter) encode(v interface{}) error {
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	// JSON is valid YAML, decoding it into a node keeps the order of the fields.
+	var node yaml.Node
+	if err := yaml.Unmarshal(buf, &node); err != nil {
+		return err
+	}
+	blockStyle(&node)
+	enc := yaml.NewEncoder(f.w)
+	enc.SetIndent(2)
+	if err := enc.Encode(&node); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// blockStyle clears the flow style and quotes that the node inherited from JSON. The encoder still
+// quotes the strings that would otherwise be read as another type.
+func blockStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, n := range node.Content {
+		blockStyle(n)
+	}
+}
+
+func (f yamlFormatter) lexeme(lexeme *types.Lexeme, nameStatus int, mode PrintMode) error {
+	return f.encode(newLexemeOutput(lexeme, nameStatus))
+}
+
+func (f yamlFormatter) lexemes(lexemes []*types.Lexeme) error {
+	values := []lexemeOutput{}
+	for _, lex := range lexemes {
+		values = append(values, newLexemeOutput(lex, existingEntry))
+	}
+	return f.encode(values)
+}
+
+func (f yamlFormatter) stats(stats []types.Stat) error {
+	if stats == nil {
+		stats = []types.Stat{}
+	}
+	return f.encode(stats)
+}
+
+func (f yamlFormatter) wods(wods []*types.Wod) error {
+	if wods == nil {
+		wods = []*types.Wod{}
+	}
+	return f.encode(wods)
+}
+
+// markdownFormatter writes Markdown documents. Single lexemes use the Markdown renderer, lists use
+// the layout of the Markdown exporter.
+type markdownFormatter struct {
+	w   io.Writer
+	app *app
+}
+
+func (f markdownFormatter) lexeme(lexeme *types.Lexeme, nameStatus int, mode PrintMode) error {
+	r, err := render.New(render.Markdown)
+	if err != nil {
+		return err
+	}
+	return r.Render(f.w, renderEntry(f.app, lexeme, nameStatus, mode))
+}
+
+func (f markdownFormatter) lexemes(lexemes []*types.Lexeme) error {
+	return exporter.Export(exporter.Markdown, f.w, lexemes)
+}
+
+func (f markdownFormatter) stats(stats []types.Stat) error {
+	rows := [][]string{}
+	for _, stat := range stats {
+		rows = append(rows, []string{stat.Name, fmt.Sprintf("%v", stat.Value)})
+	}
+	return f.table("Stats", []string{"Name", "Value"}, rows)
+}
+
+func (f markdownFormatter) wods(wods []*types.Wod) error {
+	rows := [][]string{}
+	for _, wod := range wods {
+		rows = append(rows, []string{wod.Date, wod.Word})
+	}
+	return f.table("Words of the day", []string{"Date", "Word"}, rows)
+}
+
+func (f markdownFormatter) table(title string, header []string, rows [][]string) error {
+	var out strings.Builder
+	_, _ = fmt.Fprintf(&out, "# %s\n\n", title)
+	_, _ = fmt.Fprintf(&out, "| %s |\n", strings.Join(header, " | "))
+	_, _ = fmt.Fprintf(&out, "|%s\n", strings.Repeat(" --- |", len(header)))
+	for _, row := range rows {
+		for i, cell := range row {
+			row[i] = strings.ReplaceAll(cell, "|", `\|`)
+		}
+		_, _ = fmt.Fprintf(&out, "| %s |\n", strings.Join(row, " | "))
+	}
+	_, err := io.WriteString(f.w, out.String())
+	return err
+}
+
+// lexemeOutput is the JSON representation of a lexeme. Unlike types.Lexeme, the definition is
+// embedded as a JSON document instead of a string.
+type lexemeOutput struct {
+	Name       string          `json:"name"`
+	Status     string          `json:"status,omitempty"`
+	Source     string          `json:"source,omitempty"`
+	CreatedAt  *time.Time      `json:"created_at,omitempty"`
+	UpdatedAt  *time.Time      `json:"updated_at,omitempty"`
+	Tags       []string        `json:"tags,omitempty"`
+	Notes      string          `json:"notes,omitempty"`
+	Definition json.RawMessage `json:"definition,omitempty"`
+}
+
+func newLexemeOutput(lexeme *types.Lexeme, nameStatus int) lexemeOutput {
+	out := lexemeOutput{
+		Name:      lexeme.Name,
+		Status:    strings.ToLower(strings.TrimSpace(labelName(nameStatus))),
+		Source:    lexeme.Source,
+		CreatedAt: lexeme.CreatedAt,
+		UpdatedAt: lexeme.UpdatedAt,
+		Tags:      lexeme.Tags,
+		Notes:     lexeme.Notes,
+	}
+	if json.Valid([]byte(lexeme.Definition)) {
+		out.Definition = json.RawMessage(lexeme.Definition)
+	}
+	return out
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"lexicon/render"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// terminalHeight returns the number of rows of the terminal, or zero if the standard output isn't a
+// terminal.
+func terminalHeight() int {
+	fd := int(os.Stdout.Fd())
+	if !term.IsTerminal(fd) {
+		return 0
+	}
+	_, height, err := term.GetSize(fd)
+	if err != nil {
+		return 0
+	}
+	return height
+}
+
+// lineLimit returns the number of lines after which short output is cut: --lines if set, otherwise
+// abridge_lines, otherwise the height of the terminal. Zero means no limit.
+func (a *app) lineLimit() int {
+	if a.lines != nil {
+		return *a.lines
+	}
+	if a.profile.AbridgeLines > 0 {
+		return a.profile.AbridgeLines
+	}
+	if height := terminalHeight(); height > 0 {
+		// Leave room for the "more entries" line and the prompt.
+		return height - 3
+	}
+	return 0
+}
+
+// printEntries writes the dictionary entries of e that fit the screen. The rest are kept in
+// a.pending so that the interactive session can show them on demand.
+func printEntries(a *app, r render.Renderer, e render.Entry) error {
+	a.pending = nil
+	n, err := render.Fit(os.Stdout, r, e, a.lineLimit())
+	if err != nil {
+		return err
+	}
+	if more := len(e.Definition.Entries) - n; more > 0 {
+		rest := e.Skip(n)
+		a.pending = &rest
+		entries := "entries"
+		if more == 1 {
+			entries = "entry"
+		}
+		if a.interactive {
+			fmt.Printf("\n%d more %s, press Enter to see them\n", more, entries)
+		} else {
+			fmt.Printf("\n%d more %s, run with --full or --lines 0 to see them\n", more, entries)
+		}
+	}
+	return nil
+}
+
+// showPending writes the entries left out by the last printEntries.
+func (a *app) showPending() error {
+	r, err := newRenderer(&a.profile)
+	if err != nil {
+		return err
+	}
+	return printEntries(a, r, *a.pending)
+}
+
+// page writes out to the standard output, through a pager if it doesn't fit the terminal. $PAGER
+// is used if set, then less, and the built-in pager as a last resort.
+func page(ctx context.Context, a *app, out string) error {
+	height := terminalHeight()
+	if height == 0 || strings.Count(out, "\n") < height {
+		_, err := io.WriteString(os.Stdout, out)
+		return err
+	}
+
+	pager := os.Getenv("PAGER")
+	if pager == "" {
+		if _, err := exec.LookPath("less"); err == nil {
+			pager = "less"
+		}
+	}
+	if pager == "" {
+		return builtinPager(ctx, a, out, height)
+	}
+
+	args := strings.Fields(pager)
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = strings.NewReader(out)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if os.Getenv("LESS") == "" {
+		// Keep the colors and don't clear the screen on exit, like git does.
+		cmd.Env = append(os.Environ(), "LESS=FRX")
+	}
+	return cmd.Run()
+}
+
+// builtinPager writes out a screen at a time, waiting for Enter between screens.
+func builtinPager(ctx context.Context, a *app, out string, height int) error {
+	lines := strings.SplitAfter(out, "\n")
+	total := len(lines)
+	for len(lines) > 0 {
+		n := height - 1
+		if n > len(lines) {
+			n = len(lines)
+		}
+		fmt.Print(strings.Join(lines[:n], ""))
+		lines = lines[n:]
+		if len(lines) == 0 {
+			break
+		}
+
+		prompt := fmt.Sprintf("-- More (%d%%), Enter to continue, q to quit --", 100*(total-len(lines))/total)
+		input, err := a.readLine(ctx, prompt)
+		if err != nil {
+			fmt.Println()
+			return nil
+		}
+		if strings.TrimSpace(input) == "q" {
+			return nil
+		}
+	}
+	return nil
+}
+
+// lineInput reads the lines typed by the user.
+type lineInput interface {
+	// readLine shows prompt and reads a line. It returns io.EOF at the end of the input and the error
+	// of ctx if it's cancelled first.
+	readLine(ctx context.Context, prompt string) (string, error)
+}
+
+// readLine reads a line from the standard input, see lineInput.
+func (a *app) readLine(ctx context.Context, prompt string) (string, error) {
+	if a.input == nil {
+		a.input = newLineReader(os.Stdin)
+	}
+	return a.input.readLine(ctx, prompt)
+}
+
+// lineReader reads lines in a goroutine so that waiting for input can be abandoned when the context
+// is cancelled. Lines are only read on request so that the interactive session and the pager can
+// share the input.
+type lineReader struct {
+	requests chan struct{}
+	lines    chan string
+	waiting  bool // A line was requested but not received yet.
+}
+
+func newLineReader(r io.Reader) *lineReader {
+	lr := &lineReader{requests: make(chan struct{}), lines: make(chan string)}
+	go func() {
+		defer close(lr.lines)
+		reader := bufio.NewReader(r)
+		for range lr.requests {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				if err != io.EOF {
+					log.Printf("Unable to read input: %s", err)
+				}
+				if line == "" {
+					return
+				}
+			}
+			lr.lines <- line
+		}
+	}()
+	return lr
+}
+
+func (lr *lineReader) readLine(ctx context.Context, prompt string) (string, error) {
+	fmt.Print(prompt)
+	if !lr.waiting {
+		select {
+		case lr.requests <- struct{}{}:
+			lr.waiting = true
+		case <-lr.lines:
+			// Without a pending request the channel only yields when it's closed.
+			return "", io.EOF
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+
+	select {
+	case line, ok := <-lr.lines:
+		lr.waiting = false
+		if !ok {
+			return "", io.EOF
+		}
+		return line, nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
@@ -0,0 +1,56 @@
+package render
+
+import (
+	"html/template"
+	"io"
+)
+
+// htmlRenderer writes an entry as an HTML fragment that can be embedded in a page.
+type htmlRenderer struct{}
+
+var htmlTemplate = template.Must(template.New("entry").Funcs(template.FuncMap{
+	"heading":        Heading,
+	"pronunciations": Pronunciations,
+	"quoteSource":    quoteSource,
+	"labels":         Labels,
+	"spellings":      Spellings,
+}).Parse(`<article class="lexeme{{if .Continued}} continued{{end}}" id="{{.Name}}">
+{{- if not .Continued}}
+<h2>{{.Name}}{{if .Status}} <small class="status">{{.Status}}</small>{{end}}</h2>
+<p class="added">Added on {{.Added}}{{with .Lexeme.Tags}} · {{range $i, $t := .}}{{if $i}}, {{end}}<span class="tag">{{$t}}</span>{{end}}{{end}}</p>
+{{- with .Lexeme.Notes}}
+<p class="notes">{{.}}</p>{{end}}{{end}}
+{{- range .Definition.Entries}}{{with heading .}}
+<section class="entry">
+<h3>{{.}}</h3>{{end}}
+{{- with pronunciations .Headword}}
+<p class="pronunciation">\{{.}}\</p>{{end}}
+{{- with .ShortDefinitions}}
+<ul class="short">{{range .}}<li>{{.}}</li>{{end}}</ul>{{end}}
+{{- if $.Full}}{{with labels .}}
+<p class="labels">{{.}}</p>{{end}}{{range .Defs}}
+<div class="def">{{with .VerbDivider}}<p class="vd">{{.}}</p>{{end}}
+<ol>{{range .Senses}}<li>{{with .Number}}<b>{{.}}</b> {{end}}{{.Text}}
+{{- range .UsageNotes}}<p class="usage">{{.}}</p>{{end}}
+{{- range .VerbalIllustrations}}<blockquote>{{.}}</blockquote>{{end}}</li>{{end}}</ol>
+</div>{{end}}
+{{- with .Usages}}
+<h4>Usage</h4>{{template "paragraphs" .}}{{end}}
+{{- with .SynonymDiscussions}}
+<h4>Synonym discussion</h4>{{template "paragraphs" .}}{{end}}
+{{- with spellings .}}
+<h4>Also spelled</h4>
+<ul class="spellings">{{range .}}<li>{{.}}</li>{{end}}</ul>{{end}}
+{{- with .Quotes}}
+<h4>Quotes</h4>{{range .}}
+<blockquote class="quote">{{.Text}}<footer>{{quoteSource .}}</footer></blockquote>{{end}}{{end}}{{end}}
+{{- if heading .}}
+</section>{{end}}{{end}}
+</article>
+{{define "paragraphs"}}{{range .}}
+<div class="paragraph">{{with .Label}}<p class="label">{{.}}</p>{{end}}<p>{{.Text}}</p>
+{{- range .VerbalIllustrations}}<blockquote>{{.}}</blockquote>{{end}}</div>{{end}}{{end}}`))
+
+func (htmlRenderer) Render(w io.Writer, e Entry) error {
+	return htmlTemplate.Execute(w, e)
+}
@@ -0,0 +1,117 @@
+package render
+
+import (
+	"fmt"
+	"io"
+	"lexicon/types"
+	"strings"
+)
+
+// markdownRenderer writes an entry as a Markdown section.
+type markdownRenderer struct{}
+
+func (markdownRenderer) Render(w io.Writer, e Entry) error {
+	var out strings.Builder
+	if !e.Continued {
+		_, _ = fmt.Fprintf(&out, "## %s\n\n", e.Name)
+		meta := []string{"Added on " + e.Added}
+		if e.Status != "" {
+			meta = append(meta, e.Status)
+		}
+		if len(e.Lexeme.Tags) > 0 {
+			meta = append(meta, "Tags: "+strings.Join(e.Lexeme.Tags, ", "))
+		}
+		_, _ = fmt.Fprintf(&out, "*%s*\n", strings.Join(meta, " · "))
+		if e.Lexeme.Notes != "" {
+			_, _ = fmt.Fprintf(&out, "\n%s\n", e.Lexeme.Notes)
+		}
+	}
+
+	for _, entry := range e.Definition.Entries {
+		heading := Heading(entry)
+		if heading == "" {
+			continue
+		}
+		_, _ = fmt.Fprintf(&out, "\n### %s\n", heading)
+		if prons := Pronunciations(entry.Headword); prons != "" {
+			_, _ = fmt.Fprintf(&out, "\n\\\\%s\\\\\n", prons)
+		}
+		if len(entry.ShortDefinitions) > 0 {
+			_, _ = fmt.Fprintf(&out, "\n")
+			for _, sd := range entry.ShortDefinitions {
+				_, _ = fmt.Fprintf(&out, "- %s\n", sd)
+			}
+		}
+		if e.Full {
+			markdownFull(&out, entry)
+		}
+	}
+	_, err := io.WriteString(w, out.String())
+	return err
+}
+
+func markdownFull(out *strings.Builder, entry types.Entry) {
+	if labels := Labels(entry); labels != "" {
+		_, _ = fmt.Fprintf(out, "\n*%s*\n", labels)
+	}
+	for _, d := range entry.Defs {
+		_, _ = fmt.Fprintf(out, "\n")
+		if d.VerbDivider != "" {
+			_, _ = fmt.Fprintf(out, "*%s*\n\n", d.VerbDivider)
+		}
+		for _, s := range d.Senses {
+			if s.Number != "" {
+				_, _ = fmt.Fprintf(out, "- **%s** %s\n", s.Number, s.Text)
+			} else {
+				_, _ = fmt.Fprintf(out, "- %s\n", s.Text)
+			}
+			for _, u := range s.UsageNotes {
+				_, _ = fmt.Fprintf(out, "  - %s\n", u)
+			}
+			for _, vi := range s.VerbalIllustrations {
+				_, _ = fmt.Fprintf(out, "  > %s\n", vi)
+			}
+		}
+	}
+	markdownParagraphs(out, "Usage", entry.Usages)
+	markdownParagraphs(out, "Synonym discussion", entry.SynonymDiscussions)
+	if spellings := Spellings(entry); len(spellings) > 0 {
+		_, _ = fmt.Fprintf(out, "\n#### Also spelled\n\n")
+		for _, s := range spellings {
+			_, _ = fmt.Fprintf(out, "- %s\n", s)
+		}
+	}
+	if len(entry.Quotes) > 0 {
+		_, _ = fmt.Fprintf(out, "\n#### Quotes\n")
+		for _, q := range entry.Quotes {
+			_, _ = fmt.Fprintf(out, "\n> %s\n>\n> — %s\n", q.Text, quoteSource(q))
+		}
+	}
+}
+
+func markdownParagraphs(out *strings.Builder, title string, paragraphs []types.Paragraph) {
+	if len(paragraphs) == 0 {
+		return
+	}
+	_, _ = fmt.Fprintf(out, "\n#### %s\n", title)
+	for _, p := range paragraphs {
+		if p.Label != "" {
+			_, _ = fmt.Fprintf(out, "\n**%s**\n", p.Label)
+		}
+		_, _ = fmt.Fprintf(out, "\n%s\n", p.Text)
+		for _, vi := range p.VerbalIllustrations {
+			_, _ = fmt.Fprintf(out, "\n> %s\n", vi)
+		}
+	}
+}
+
+// quoteSource returns the attribution of a quote, skipping the missing parts.
+func quoteSource(q types.Quote) string {
+	var parts []string
+	for _, p := range []string{q.Author, q.Source, q.PublicationDate} {
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
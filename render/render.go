@@ -0,0 +1,163 @@
+// render lays out lexicon entries for display. A Renderer decides what parts of an entry are shown
+// and in which order; the terminal, plain, Markdown and HTML renderers are built in and teams can
+// supply their own layout as a Go text/template.
+package render
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"lexicon/types"
+	"lexicon/util"
+	"sort"
+	"strings"
+)
+
+// Renderer writes an entry to w.
+type Renderer interface {
+	Render(w io.Writer, e Entry) error
+}
+
+// Built-in renderers.
+const (
+	Terminal = "terminal"
+	Plain    = "plain"
+	Markdown = "markdown"
+	HTML     = "html"
+)
+
+// Names returns the names of the built-in renderers sorted alphabetically.
+func Names() []string {
+	names := []string{Terminal, Plain, Markdown, HTML}
+	sort.Strings(names)
+	return names
+}
+
+// New returns a built-in renderer.
+func New(name string) (Renderer, error) {
+	switch name {
+	case Terminal:
+		return NewTerminal(true), nil
+	case Plain:
+		return NewTerminal(false), nil
+	case Markdown:
+		return markdownRenderer{}, nil
+	case HTML:
+		return htmlRenderer{}, nil
+	}
+	return nil, fmt.Errorf("unknown renderer %q, expected one of: %s", name, strings.Join(Names(), ", "))
+}
+
+// Entry is what renderers show: a lexeme along with its parsed definition. Templates receive an Entry
+// as their data.
+type Entry struct {
+	Name       string
+	Status     string // "New", "Updated" or empty for words that were already in the lexicon.
+	Added      string // Creation time formatted for display.
+	Full       bool   // Show every sense and quote instead of the short definitions only.
+	Continued  bool   // The header was already shown, only the dictionary entries are left.
+	Lexeme     *types.Lexeme
+	Definition types.Definition
+}
+
+// NewEntry parses the definition of lexeme. If the definition can't be parsed the entry is still
+// returned, without definitions, along with the error.
+func NewEntry(lexeme *types.Lexeme, status, added string, full bool) (Entry, error) {
+	e := Entry{Name: lexeme.Name, Status: status, Added: added, Full: full, Lexeme: lexeme}
+	if err := json.Unmarshal([]byte(lexeme.Definition), &e.Definition); err != nil {
+		return e, fmt.Errorf("unable to parse definition: %s", err)
+	}
+	return e, nil
+}
+
+// Skip returns the entry without its first n dictionary entries, marked as continued.
+func (e Entry) Skip(n int) Entry {
+	e.Definition.Entries = e.Definition.Entries[n:]
+	e.Continued = true
+	return e
+}
+
+// Fit writes as many dictionary entries of e as fit in maxLines lines, and at least one, so that
+// words with several entries aren't cut in the middle of one. It returns the number of entries
+// written. Zero maxLines means no limit.
+func Fit(w io.Writer, r Renderer, e Entry, maxLines int) (int, error) {
+	entries := e.Definition.Entries
+	var out bytes.Buffer
+	if err := r.Render(&out, e); err != nil {
+		return 0, err
+	}
+	n := len(entries)
+	if maxLines > 0 && n > 1 && lineCount(out.Bytes()) > maxLines {
+		// Add entries until the output no longer fits.
+		var fit bytes.Buffer
+		for n = 1; n <= len(entries); n++ {
+			out.Reset()
+			e.Definition.Entries = entries[:n]
+			if err := r.Render(&out, e); err != nil {
+				return 0, err
+			}
+			if n > 1 && lineCount(out.Bytes()) > maxLines {
+				break
+			}
+			fit.Reset()
+			fit.Write(out.Bytes())
+		}
+		n--
+		out = fit
+	}
+	_, err := w.Write(out.Bytes())
+	return n, err
+}
+
+func lineCount(b []byte) int {
+	return bytes.Count(b, []byte("\n"))
+}
+
+// Pronunciations returns the pronunciations of a headword separated by commas.
+func Pronunciations(headword types.Headword) string {
+	var prons []string
+	for _, p := range headword.Pronunciations {
+		prons = append(prons, p.Text)
+	}
+	return strings.Join(prons, ",")
+}
+
+// Cognates returns the cross-references of an entry, e.g. `less common spelling of "color"`.
+func Cognates(cognates []types.Cognate) string {
+	var res []string
+	for _, cognate := range cognates {
+		res = append(res, cognate.Label+" "+strings.Join(util.QuoteStrings(cognate.Targets), ","))
+	}
+	return strings.Join(res, " | ")
+}
+
+// Heading returns the title of an entry: the headword and its grammatical function, or the
+// cross-references of entries without one. It returns an empty string if there's nothing to show.
+func Heading(e types.Entry) string {
+	if len(e.GrammaticalFunction) > 0 {
+		return fmt.Sprintf("%s — %s", e.Headword.Text, e.GrammaticalFunction)
+	}
+	if len(e.Cognates) > 0 {
+		return fmt.Sprintf("%s — %s", e.Headword.Text, Cognates(e.Cognates))
+	}
+	return ""
+}
+
+// Labels returns the general and subject labels of an entry, e.g. "often capitalized, British".
+func Labels(e types.Entry) string {
+	return strings.Join(append(append([]string(nil), e.Labels...), e.SubjectLabels...), ", ")
+}
+
+// Spellings returns the other spellings of an entry: its variants with their labels, e.g.
+// "or colour", and its alternate headwords.
+func Spellings(e types.Entry) []string {
+	var res []string
+	for _, v := range e.Variants {
+		res = append(res, strings.TrimSpace(v.Label+" "+v.Text))
+	}
+	for _, hw := range e.AlternateHeadwords {
+		res = append(res, hw.Text)
+	}
+	return res
+}
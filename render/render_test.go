@@ -0,0 +1,69 @@
+package render_test
+
+import (
+	"bytes"
+	"io/fs"
+	"lexicon/dictapi"
+	"lexicon/dictapi/dictapitest"
+	"lexicon/render"
+	"lexicon/types"
+	"lexicon/util"
+	"strings"
+	"testing"
+)
+
+// fixtureEntry returns an entry with the definitions of the fixtures of words.
+func fixtureEntry(t *testing.T, full bool, words ...string) render.Entry {
+	t.Helper()
+	var def types.Definition
+	for _, word := range words {
+		body, err := fs.ReadFile(dictapitest.Fixtures(), "collegiate/"+word+".json")
+		if err != nil {
+			t.Fatal(err)
+		}
+		d, _, err := dictapi.Parse(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		def.Entries = append(def.Entries, d.Entries...)
+	}
+	b, err := util.Serialize(def)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := render.NewEntry(&types.Lexeme{Name: words[0], Definition: string(b)}, "", "", full)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func TestRenderSections(t *testing.T) {
+	sections := []string{"Usage", "Synonym discussion", "Also spelled"}
+	for _, name := range render.Names() {
+		r, err := render.New(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var out bytes.Buffer
+		if err := r.Render(&out, fixtureEntry(t, true, "accept", "ephemeral")); err != nil {
+			t.Fatalf("%s: Render: %s", name, err)
+		}
+		for _, s := range append(sections, "Accept vs. Except", "often plural, botany", "less commonly") {
+			if !strings.Contains(out.String(), s) {
+				t.Errorf("%s: full output doesn't contain %q:\n%s", name, s, out.String())
+			}
+		}
+
+		out.Reset()
+		if err := r.Render(&out, fixtureEntry(t, false, "accept", "ephemeral")); err != nil {
+			t.Fatalf("%s: Render: %s", name, err)
+		}
+		for _, s := range sections {
+			if strings.Contains(out.String(), s) {
+				t.Errorf("%s: short output contains %q:\n%s", name, s, out.String())
+			}
+		}
+	}
+}
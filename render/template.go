@@ -0,0 +1,54 @@
+package render
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// templateRenderer lays out entries with a user supplied text/template.
+type templateRenderer struct {
+	tmpl *template.Template
+}
+
+// Funcs are the functions available to templates besides the text/template builtins.
+var Funcs = template.FuncMap{
+	"heading":        Heading,
+	"pronunciations": Pronunciations,
+	"cognates":       Cognates,
+	"quoteSource":    quoteSource,
+	"labels":         Labels,
+	"spellings":      Spellings,
+	"join":           strings.Join,
+	"repeat":         strings.Repeat,
+	"upper":          strings.ToUpper,
+}
+
+// NewTemplate returns a renderer that executes text with an Entry as data, e.g.
+//
+//	{{.Name}} ({{.Added}})
+//	{{range .Definition.Entries}}{{range .Etymology}}{{.}}
+//	{{end}}{{range .ShortDefinitions}}- {{.}}
+//	{{end}}{{end}}
+func NewTemplate(name, text string) (Renderer, error) {
+	tmpl, err := template.New(name).Funcs(Funcs).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	return templateRenderer{tmpl: tmpl}, nil
+}
+
+// ParseTemplateFile reads a template from a file, see NewTemplate.
+func ParseTemplateFile(path string) (Renderer, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewTemplate(filepath.Base(path), string(buf))
+}
+
+func (r templateRenderer) Render(w io.Writer, e Entry) error {
+	return r.tmpl.Execute(w, e)
+}
@@ -0,0 +1,130 @@
+package render
+
+import (
+	"fmt"
+	"io"
+	"lexicon/types"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// terminalRenderer writes entries as text meant to be read on a terminal. Colors are only used if
+// enabled and the color package didn't disable them, e.g. because the output isn't a terminal.
+type terminalRenderer struct {
+	title, label, subtitle *color.Color
+}
+
+// NewTerminal returns the renderer of the terminal, or of plain text if colored is false.
+func NewTerminal(colored bool) Renderer {
+	r := terminalRenderer{
+		title:    color.New(color.FgGreen, color.Bold),
+		label:    color.New(color.FgRed),
+		subtitle: color.New(color.FgBlue),
+	}
+	if !colored {
+		r.title.DisableColor()
+		r.label.DisableColor()
+		r.subtitle.DisableColor()
+	}
+	return r
+}
+
+func (r terminalRenderer) Render(w io.Writer, e Entry) error {
+	var out strings.Builder
+	if !e.Continued {
+		_, _ = fmt.Fprintf(&out, "%s", r.title.Sprintf("\n%s", e.Name))
+		if e.Status != "" {
+			_, _ = fmt.Fprintf(&out, "\t\t%s", r.label.Sprint(e.Status))
+		}
+		_, _ = fmt.Fprintf(&out, "%s", r.title.Sprintf("\n%s\n", strings.Repeat("=", len(e.Name))))
+		_, _ = fmt.Fprintf(&out, "Added on %s\n", e.Added)
+	}
+
+	entries := e.Definition.Entries
+	for i, entry := range entries {
+		out.WriteString(r.entry(entry, e.Full, i+1 < len(entries)))
+	}
+	_, err := io.WriteString(w, out.String())
+	return err
+}
+
+func (r terminalRenderer) entry(e types.Entry, full, more bool) string {
+	var out strings.Builder
+	_, _ = fmt.Fprintf(&out, "\n")
+	if heading := Heading(e); heading != "" {
+		if prons := Pronunciations(e.Headword); prons != "" && len(e.GrammaticalFunction) > 0 {
+			heading += fmt.Sprintf("    (%s)", prons)
+		}
+		_, _ = fmt.Fprintf(&out, "%s\n", r.subtitle.Sprint(heading))
+		if len(e.GrammaticalFunction) > 0 {
+			for _, sd := range e.ShortDefinitions {
+				_, _ = fmt.Fprintf(&out, "• %s\n", sd)
+			}
+		}
+	}
+	if !full {
+		return out.String()
+	}
+
+	if labels := Labels(e); labels != "" {
+		_, _ = fmt.Fprintf(&out, "%s\n", labels)
+	}
+	for _, d := range e.Defs {
+		r.def(&out, d)
+	}
+	r.paragraphs(&out, "Usage", e.Usages)
+	r.paragraphs(&out, "Synonym discussion", e.SynonymDiscussions)
+	if spellings := Spellings(e); len(spellings) > 0 {
+		_, _ = fmt.Fprintf(&out, "\n%s\n", r.subtitle.Sprint("Also spelled"))
+		for _, s := range spellings {
+			_, _ = fmt.Fprintf(&out, "• %s\n", s)
+		}
+	}
+	if len(e.Quotes) > 0 {
+		_, _ = fmt.Fprintf(&out, "\n%s\n", r.subtitle.Sprint("Quotes"))
+		for _, q := range e.Quotes {
+			_, _ = fmt.Fprintf(&out, "  %q\n", q.Text)
+			_, _ = fmt.Fprintf(&out, "  %s, %s, %s\n\n", q.Source, q.Author, q.PublicationDate)
+		}
+	}
+	if more {
+		_, _ = fmt.Fprintf(&out, "%s\n", strings.Repeat("—", 80))
+	}
+	return out.String()
+}
+
+func (r terminalRenderer) def(out *strings.Builder, d types.Def) {
+	_, _ = fmt.Fprintf(out, "\n")
+	if len(d.VerbDivider) > 0 {
+		_, _ = fmt.Fprintf(out, "%s\n", d.VerbDivider)
+	}
+	for _, s := range d.Senses {
+		_, _ = fmt.Fprintf(out, "%s\n", s.Text)
+		for _, u := range s.UsageNotes {
+			_, _ = fmt.Fprintf(out, "  •%q\n", u)
+		}
+		if len(s.VerbalIllustrations) > 0 {
+			for _, i := range s.VerbalIllustrations {
+				_, _ = fmt.Fprintf(out, "  • %q\n", i)
+			}
+			_, _ = fmt.Fprintf(out, "\n")
+		}
+	}
+}
+
+func (r terminalRenderer) paragraphs(out *strings.Builder, title string, paragraphs []types.Paragraph) {
+	if len(paragraphs) == 0 {
+		return
+	}
+	_, _ = fmt.Fprintf(out, "\n%s\n", r.subtitle.Sprint(title))
+	for _, p := range paragraphs {
+		if p.Label != "" {
+			_, _ = fmt.Fprintf(out, "%s\n", p.Label)
+		}
+		_, _ = fmt.Fprintf(out, "%s\n", p.Text)
+		for _, i := range p.VerbalIllustrations {
+			_, _ = fmt.Fprintf(out, "  • %q\n", i)
+		}
+	}
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"lexicon/dictapi"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mattn/go-isatty"
+	"github.com/peterh/liner"
+)
+
+// metaCommands are the commands of the CLI that can be run in the interactive session as
+// ":<command> [flags] [args]".
+var metaCommands = []string{"rm", "tag", "note", "wod", "stats", "search"}
+
+// sessionCommands only exist in the interactive session.
+var sessionCommands = []struct{ name, short string }{
+	{"full", "Print the full definition of the last word."},
+	{"help", "Print this help, or \":help <command>\" for the flags of a command."},
+	{"exit", "End the session, like Ctrl-D."},
+}
+
+// interactive launches an interactive session where the user can define as many words as needed.
+// Lines starting with ":" are meta-commands, "word!" prints the full definition of a word and an
+// empty line shows the entries of the last word that didn't fit the screen. The session ends on EOF,
+// ":exit", "!exit", Ctrl-C while waiting for input that isn't read from a terminal, or when ctx is
+// cancelled. Ctrl-C while a line is handled only interrupts that line.
+func interactive(ctx context.Context, app *app) {
+	app.interactive = true
+	if isatty.IsTerminal(os.Stdin.Fd()) && isatty.IsTerminal(os.Stdout.Fd()) {
+		e := newEditor(app)
+		defer e.close()
+		app.input = e
+	}
+
+	for ctx.Err() == nil {
+		lineCtx, stop := notifyContext(ctx, os.Interrupt)
+		line, err := app.readLine(lineCtx, "\n> ")
+		if errors.Is(err, errAborted) {
+			stop()
+			continue
+		}
+		if err != nil {
+			if lineCtx.Err() != nil {
+				fmt.Println()
+			}
+			stop()
+			return
+		}
+
+		input := strings.TrimSpace(line)
+		if input == "!exit" || input == ":exit" {
+			stop()
+			return
+		}
+		handleLine(lineCtx, app, input)
+		stop()
+	}
+}
+
+// handleLine defines a word or runs the command in a line of the interactive session.
+func handleLine(ctx context.Context, app *app, input string) {
+	// Empty lines show the entries left out of the last word, if any
+	if len(input) == 0 {
+		if app.pending != nil {
+			if err := app.showPending(); err != nil {
+				log.Printf("Unable to show entries: %s", err)
+			}
+		}
+		return
+	}
+
+	if strings.HasPrefix(input, ":") {
+		runMetaCommand(ctx, app, input)
+		return
+	}
+
+	// "word!" shows the full definition regardless of the mode
+	input = normalizeName(input)
+	mode := app.mode()
+	if strings.HasSuffix(input, "!") && input != "!" {
+		input = strings.TrimSpace(strings.TrimSuffix(input, "!"))
+		mode = FullDef
+	}
+	app.words = nil
+	if err := defineName(ctx, app, input, mode); err != nil {
+		var serr *dictapi.SuggestionsError
+		if errors.As(err, &serr) {
+			app.suggestions = serr.Suggestions
+		}
+		log.Printf("Unable to define %q: %s", input, err)
+	}
+}
+
+// runMetaCommand runs a line like ":tag apple fruit".
+func runMetaCommand(ctx context.Context, app *app, input string) {
+	fields := strings.Fields(strings.TrimPrefix(input, ":"))
+	if len(fields) == 0 {
+		sessionHelp(app)
+		return
+	}
+	name, args := fields[0], fields[1:]
+	switch name {
+	case "full":
+		if app.last == nil {
+			log.Printf("No word to expand yet")
+			return
+		}
+		if err := newFormatter(ctx, app).lexeme(app.last, existingEntry, FullDef); err != nil {
+			log.Printf("Unable to print %q: %s", app.last.Name, err)
+		}
+		return
+	case "help":
+		if len(args) > 0 {
+			if cmd := app.find(args[0]); cmd != nil && isMetaCommand(args[0]) {
+				cmd.usage(os.Stdout)
+				return
+			}
+		}
+		sessionHelp(app)
+		return
+	}
+
+	cmd := app.find(name)
+	if cmd == nil || !isMetaCommand(name) {
+		log.Printf("Unknown command %q, type :help to list the commands", name)
+		return
+	}
+	positional, err := cmd.parse(args)
+	if errors.Is(err, flag.ErrHelp) {
+		cmd.usage(os.Stdout)
+		return
+	}
+	if err == nil {
+		err = cmd.run(ctx, app, positional)
+	}
+	// Commands may change the saved words.
+	app.words = nil
+	var uerr usageError
+	if errors.As(err, &uerr) {
+		fmt.Printf("%s\n\n", err)
+		cmd.usage(os.Stdout)
+	} else if err != nil {
+		log.Printf("%s failed with error: %s", name, err)
+	}
+}
+
+func isMetaCommand(name string) bool {
+	for _, c := range metaCommands {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
+
+func sessionHelp(app *app) {
+	fmt.Printf("Type a word to define it, or \"word!\" to see its full definition. Press Enter on an\n")
+	fmt.Printf("empty line to see the entries that didn't fit the screen.\n\nCommands:\n")
+	for _, c := range sessionCommands {
+		fmt.Printf("  :%-28s %s\n", c.name, c.short)
+	}
+	for _, name := range metaCommands {
+		cmd := app.find(name)
+		fmt.Printf("  :%-28s %s\n", name+" "+cmd.args, cmd.short)
+	}
+}
+
+// errAborted is returned by the editor when the user presses Ctrl-C.
+var errAborted = errors.New("aborted")
+
+// editor reads the input of interactive sessions on a terminal, with line editing, a history that
+// is kept across sessions and completion of saved words and commands.
+type editor struct {
+	state   *liner.State
+	history string
+}
+
+func newEditor(app *app) *editor {
+	e := &editor{state: liner.NewLiner()}
+	e.state.SetCtrlCAborts(true)
+	e.state.SetTabCompletionStyle(liner.TabPrints)
+	e.state.SetWordCompleter(func(line string, pos int) (string, []string, string) {
+		return app.complete(line, pos)
+	})
+
+	if app.configPath != "" {
+		e.history = filepath.Join(filepath.Dir(app.configPath), "history")
+		if f, err := os.Open(e.history); err == nil {
+			_, _ = e.state.ReadHistory(f)
+			f.Close()
+		}
+	}
+	return e
+}
+
+// readLine implements lineInput. The line editor can't be interrupted, but while it waits for input
+// Ctrl-C is read as a key rather than delivered as a signal.
+func (e *editor) readLine(ctx context.Context, prompt string) (string, error) {
+	// The prompt can't span lines.
+	for strings.HasPrefix(prompt, "\n") {
+		fmt.Println()
+		prompt = prompt[1:]
+	}
+	line, err := e.state.Prompt(prompt)
+	if errors.Is(err, liner.ErrPromptAborted) {
+		return "", errAborted
+	}
+	if err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(line) != "" && !strings.HasPrefix(prompt, "--") {
+		e.state.AppendHistory(line)
+	}
+	return line, nil
+}
+
+// close restores the terminal and saves the history.
+func (e *editor) close() {
+	if e.history != "" {
+		if err := os.MkdirAll(filepath.Dir(e.history), 0700); err == nil {
+			if f, err := os.OpenFile(e.history, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600); err == nil {
+				_, _ = e.state.WriteHistory(f)
+				f.Close()
+			}
+		}
+	}
+	_ = e.state.Close()
+}
+
+// complete returns the completions of the word under the cursor: meta-commands after ":", otherwise
+// saved words and the spelling suggestions of the last word that wasn't in the dictionary.
+func (a *app) complete(line string, pos int) (head string, completions []string, tail string) {
+	// pos counts runes, not bytes.
+	r := []rune(line)
+	if pos > len(r) {
+		pos = len(r)
+	}
+	head, tail = string(r[:pos]), string(r[pos:])
+	start := strings.LastIndex(head, " ") + 1
+	head, word := head[:start], head[start:]
+
+	var candidates []string
+	if start == 0 && strings.HasPrefix(word, ":") {
+		for _, c := range sessionCommands {
+			candidates = append(candidates, ":"+c.name)
+		}
+		for _, c := range metaCommands {
+			candidates = append(candidates, ":"+c)
+		}
+	} else {
+		candidates = append(a.savedWords(), a.suggestions...)
+	}
+
+	seen := make(map[string]bool)
+	for _, c := range candidates {
+		if strings.HasPrefix(c, strings.ToLower(word)) && !seen[c] {
+			seen[c] = true
+			completions = append(completions, c)
+		}
+	}
+	sort.Strings(completions)
+	return head, completions, tail
+}
+
+// savedWords returns the names of the saved words. They're cached until a command changes them.
+func (a *app) savedWords() []string {
+	if a.words == nil {
+		lexemes, err := a.dictionary.List(context.Background())
+		if err != nil {
+			return nil
+		}
+		a.words = []string{}
+		for _, lex := range lexemes {
+			a.words = append(a.words, lex.Name)
+		}
+	}
+	return a.words
+}
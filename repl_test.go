@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"lexicon/lexmem"
+	"lexicon/types"
+	"reflect"
+	"testing"
+)
+
+func TestComplete(t *testing.T) {
+	dictionary := lexmem.New()
+	for _, name := range []string{"naïve", "naïveté", "nadir", "ephemeral"} {
+		if err := dictionary.Save(context.Background(), &types.Lexeme{Name: name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	a := &app{dictionary: dictionary, suggestions: []string{"ephemera"}}
+
+	tests := []struct {
+		line        string
+		pos         int // In runes, like liner.
+		head, tail  string
+		completions []string
+	}{
+		{"na", 2, "", "", []string{"nadir", "naïve", "naïveté"}},
+		{"naï", 3, "", "", []string{"naïve", "naïveté"}},
+		{"naïve e", 7, "naïve ", "", []string{"ephemera", "ephemeral"}},
+		{"naïve e tail", 7, "naïve ", " tail", []string{"ephemera", "ephemeral"}},
+		{"café naï x", 8, "café ", " x", []string{"naïve", "naïveté"}},
+		{"NAÏ", 3, "", "", []string{"naïve", "naïveté"}},
+	}
+	for _, tt := range tests {
+		head, completions, tail := a.complete(tt.line, tt.pos)
+		if head != tt.head || tail != tt.tail || !reflect.DeepEqual(completions, tt.completions) {
+			t.Errorf("complete(%q, %d) = %q, %q, %q, want %q, %q, %q",
+				tt.line, tt.pos, head, completions, tail, tt.head, tt.completions, tt.tail)
+		}
+	}
+}
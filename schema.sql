@@ -4,5 +4,15 @@ CREATE TABLE "lexicon" (
     "source"        TEXT,
     "createdAt"	    INTEGER NOT NULL,
     "updatedAt"	    INTEGER NOT NULL,
+    "tags"          TEXT,
+    "notes"         TEXT,
 	PRIMARY KEY("name")
-)
+);
+CREATE TABLE "wods" (
+    "source"        TEXT NOT NULL,
+    "date"          TEXT NOT NULL,
+    "word"          TEXT NOT NULL,
+    "fetchedAt"     INTEGER NOT NULL,
+	PRIMARY KEY("source", "date")
+);
+PRAGMA user_version = 2;
@@ -1,6 +1,7 @@
 #!/usr/bin/env bash
+# createdAt is in seconds, run "lexicon db doctor --fix" if older rows are in milliseconds.
 if [[ $2 == "--timestamp" ]]; then
-	sqlite3 $DATA_SOURCE_NAME "SELECT name, datetime(createdAt / 1000, 'unixepoch') FROM lexicon WHERE createdAt >= strftime('%s', 'now') * 1000 - 86400000"
+	sqlite3 $DATA_SOURCE_NAME "SELECT name, datetime(createdAt, 'unixepoch') FROM lexicon WHERE createdAt >= strftime('%s', 'now') - 86400"
 else
-	sqlite3 $DATA_SOURCE_NAME "SELECT name FROM lexicon WHERE createdAt >= strftime('%s', 'now') * 1000 - 86400000"
+	sqlite3 $DATA_SOURCE_NAME "SELECT name FROM lexicon WHERE createdAt >= strftime('%s', 'now') - 86400"
 fi
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"lexicon/lexdb"
+	"lexicon/types"
+	"lexicon/util"
+	"log"
+	"strings"
+	"time"
+)
+
+// Conflict policies of transfer, for words that are in both dictionaries.
+const (
+	conflictSkip      = "skip"      // Keep the word of the destination.
+	conflictOverwrite = "overwrite" // Replace it with the word of the source.
+	conflictNewest    = "newest"    // Keep the one updated last.
+)
+
+var conflictPolicies = []string{conflictSkip, conflictOverwrite, conflictNewest}
+
+// transferOptions are the flags of the transfer command.
+type transferOptions struct {
+	from, to   string // Profiles of the source and destination dictionaries.
+	onConflict string // One of the conflict policies.
+}
+
+// transferSummary counts what transfer did with each word of the source.
+type transferSummary struct {
+	added, overwritten, skipped, failed int
+}
+
+// transfer copies every word of the dictionary of a profile to the dictionary of another one, with
+// its timestamps, source, tags and notes. Words that are already in the destination are handled
+// according to opts.onConflict. Both profiles are read from the configuration file only: the
+// environment variables set a single profile, and applying them to both would point them at the
+// same dictionary.
+func transfer(ctx context.Context, app *app, opts transferOptions) error {
+	if opts.from == "" || opts.to == "" {
+		return usageError{"--from and --to are required"}
+	}
+	if opts.from == opts.to {
+		return usageError{"--from and --to must be different profiles"}
+	}
+	policy := strings.ToLower(opts.onConflict)
+	if !util.Contains(conflictPolicies, policy) {
+		return usageError{fmt.Sprintf("unknown conflict policy %q, expected %s", opts.onConflict, strings.Join(conflictPolicies, ", "))}
+	}
+	if app.cfg == nil {
+		return fmt.Errorf("unable to read the configuration file %s", app.configPath)
+	}
+
+	from, err := app.cfg.Stored(opts.from)
+	if err != nil {
+		return fmt.Errorf("profile %s: %s", opts.from, err)
+	}
+	to, err := app.cfg.Stored(opts.to)
+	if err != nil {
+		return fmt.Errorf("profile %s: %s", opts.to, err)
+	}
+	if from.Backend == to.Backend && from.Backend != backendAPI && lexdb.FilePath(from.DB) == lexdb.FilePath(to.DB) {
+		return fmt.Errorf("profiles %s and %s use the same database %s", opts.from, opts.to, lexdb.FilePath(from.DB))
+	}
+
+	src, err := openDictionary(from)
+	if err != nil {
+		return fmt.Errorf("profile %s: %s", opts.from, err)
+	}
+	defer src.Close()
+	dest, err := openDictionary(to)
+	if err != nil {
+		return fmt.Errorf("profile %s: %s", opts.to, err)
+	}
+	defer dest.Close()
+
+	lexemes, err := src.List(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to list the words of %s: %s", opts.from, err)
+	}
+	existing, err := dest.List(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to list the words of %s: %s", opts.to, err)
+	}
+	saved := make(map[string]*types.Lexeme, len(existing))
+	for _, l := range existing {
+		saved[l.Name] = l
+	}
+
+	var sum transferSummary
+	for i, lexeme := range lexemes {
+		if ctx.Err() != nil {
+			log.Printf("Interrupted, %d of %d words not transferred", len(lexemes)-i, len(lexemes))
+			break
+		}
+		if err := transferLexeme(ctx, dest, lexeme, saved[lexeme.Name], policy, &sum); err != nil {
+			log.Printf("Unable to transfer %q: %s", lexeme.Name, err)
+			sum.failed++
+		}
+	}
+
+	fmt.Printf("Transferred %d words from %s to %s: %d added, %d overwritten, %d skipped, %d failed\n",
+		len(lexemes), opts.from, opts.to, sum.added, sum.overwritten, sum.skipped, sum.failed)
+	if sum.failed > 0 {
+		return fmt.Errorf("%d words couldn't be transferred", sum.failed)
+	}
+	return ctx.Err()
+}
+
+// transferLexeme writes lexeme to dest. existing is the same word in dest, nil if it's not there.
+func transferLexeme(ctx context.Context, dest types.Dictionary, lexeme, existing *types.Lexeme, policy string, sum *transferSummary) error {
+	if existing == nil {
+		err := dest.Save(ctx, lexeme)
+		if !errors.Is(err, types.AlreadyExists) {
+			if err == nil {
+				sum.added++
+			}
+			return err
+		}
+		// Saved meanwhile, it's a conflict after all.
+		if existing, err = dest.Find(ctx, lexeme.Name); err != nil {
+			return err
+		}
+	}
+
+	switch policy {
+	case conflictSkip:
+		sum.skipped++
+		return nil
+	case conflictNewest:
+		if !updatedAt(lexeme).After(updatedAt(existing)) {
+			sum.skipped++
+			return nil
+		}
+	}
+	// Upsert keeps the timestamps of the source, unlike Update.
+	if err := dest.Upsert(ctx, lexeme); err != nil {
+		return err
+	}
+	sum.overwritten++
+	return nil
+}
+
+// updatedAt returns when a lexeme was last updated, or when it was created if that's unknown.
+func updatedAt(l *types.Lexeme) time.Time {
+	switch {
+	case l.UpdatedAt != nil:
+		return *l.UpdatedAt
+	case l.CreatedAt != nil:
+		return *l.CreatedAt
+	}
+	return time.Time{}
+}
@@ -0,0 +1,343 @@
+// dictionarytest checks that implementations of types.Dictionary behave the same way. Every
+// backend runs the suite from its tests with a function that returns a new, empty dictionary:
+//
+//	func TestDictionary(t *testing.T) {
+//		dictionarytest.Run(t, func(t *testing.T) types.Dictionary {
+//			return lexmem.New()
+//		})
+//	}
+package dictionarytest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"lexicon/types"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Run runs the conformance tests, each one on a new dictionary returned by open, which is closed at
+// the end of the test.
+func Run(t *testing.T, open func(t *testing.T) types.Dictionary) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, ctx context.Context, d types.Dictionary)
+	}{
+		{"FindMissing", testFindMissing},
+		{"SaveAndFind", testSaveAndFind},
+		{"SaveKeepsTimestamps", testSaveKeepsTimestamps},
+		{"SaveDuplicate", testSaveDuplicate},
+		{"Update", testUpdate},
+		{"UpdateKeepsCreatedAt", testUpdateKeepsCreatedAt},
+		{"UpdateMissing", testUpdateMissing},
+		{"Upsert", testUpsert},
+		{"Remove", testRemove},
+		{"RemoveMissing", testRemoveMissing},
+		{"List", testList},
+		{"Stats", testStats},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			d := open(t)
+			defer func() {
+				if err := d.Close(); err != nil {
+					t.Errorf("Close: %s", err)
+				}
+			}()
+			tt.fn(t, context.Background(), d)
+		})
+	}
+}
+
+// newLexeme returns a lexeme with every field but the timestamps set.
+func newLexeme(name string) *types.Lexeme {
+	def, err := json.Marshal(types.Definition{Entries: []types.Entry{{
+		Headword:            types.Headword{Text: name},
+		GrammaticalFunction: "noun",
+		ShortDefinitions:    []string{"the definition of " + name, "with <markup> & ünïcode"},
+	}}})
+	if err != nil {
+		panic(err)
+	}
+	return &types.Lexeme{
+		Name:       name,
+		Definition: string(def),
+		Source:     "dictionarytest",
+		Tags:       []string{"test", "conformance"},
+		Notes:      "a note\nspanning two lines",
+	}
+}
+
+// at returns a time to the second, which every dictionary keeps.
+func at(s string) *time.Time {
+	t, err := time.Parse("2006-01-02 15:04:05", s)
+	if err != nil {
+		panic(err)
+	}
+	t = t.Local()
+	return &t
+}
+
+func testFindMissing(t *testing.T, ctx context.Context, d types.Dictionary) {
+	if _, err := d.Find(ctx, "missing"); !errors.Is(err, types.NotFound) {
+		t.Errorf("Find of a missing word returned %v, want types.NotFound", err)
+	}
+}
+
+func testSaveAndFind(t *testing.T, ctx context.Context, d types.Dictionary) {
+	before := time.Now().Truncate(time.Second)
+	lexeme := newLexeme("ephemeral")
+	if err := d.Save(ctx, lexeme); err != nil {
+		t.Fatalf("Save: %s", err)
+	}
+	after := time.Now()
+	if lexeme.CreatedAt == nil || lexeme.UpdatedAt == nil {
+		t.Fatalf("Save didn't set the timestamps of the lexeme")
+	}
+
+	got, err := d.Find(ctx, "ephemeral")
+	if err != nil {
+		t.Fatalf("Find: %s", err)
+	}
+	checkLexeme(t, got, lexeme)
+	for _, ts := range []*time.Time{got.CreatedAt, got.UpdatedAt} {
+		if ts.Before(before) || ts.After(after) {
+			t.Errorf("timestamp %s isn't between %s and %s", ts, before, after)
+		}
+	}
+}
+
+func testSaveKeepsTimestamps(t *testing.T, ctx context.Context, d types.Dictionary) {
+	lexeme := newLexeme("ephemeral")
+	lexeme.CreatedAt, lexeme.UpdatedAt = at("2020-01-02 03:04:05"), at("2021-02-03 04:05:06")
+	if err := d.Save(ctx, lexeme); err != nil {
+		t.Fatalf("Save: %s", err)
+	}
+	got, err := d.Find(ctx, "ephemeral")
+	if err != nil {
+		t.Fatalf("Find: %s", err)
+	}
+	checkLexeme(t, got, lexeme)
+}
+
+func testSaveDuplicate(t *testing.T, ctx context.Context, d types.Dictionary) {
+	if err := d.Save(ctx, newLexeme("ephemeral")); err != nil {
+		t.Fatalf("Save: %s", err)
+	}
+	dup := newLexeme("ephemeral")
+	dup.Notes = "the duplicate"
+	if err := d.Save(ctx, dup); !errors.Is(err, types.AlreadyExists) {
+		t.Errorf("Save of a duplicate returned %v, want types.AlreadyExists", err)
+	}
+	got, err := d.Find(ctx, "ephemeral")
+	if err != nil {
+		t.Fatalf("Find: %s", err)
+	}
+	if got.Notes == dup.Notes {
+		t.Errorf("Save of a duplicate replaced the saved word")
+	}
+}
+
+func testUpdate(t *testing.T, ctx context.Context, d types.Dictionary) {
+	lexeme := newLexeme("ephemeral")
+	lexeme.CreatedAt, lexeme.UpdatedAt = at("2020-01-02 03:04:05"), at("2020-01-02 03:04:05")
+	if err := d.Save(ctx, lexeme); err != nil {
+		t.Fatalf("Save: %s", err)
+	}
+
+	changed, err := d.Find(ctx, "ephemeral")
+	if err != nil {
+		t.Fatalf("Find: %s", err)
+	}
+	changed.Definition = newLexeme("transient").Definition
+	changed.Source = "elsewhere"
+	changed.Tags = []string{"changed"}
+	changed.Notes = "another note"
+	before := time.Now().Truncate(time.Second)
+	if err := d.Update(ctx, changed); err != nil {
+		t.Fatalf("Update: %s", err)
+	}
+
+	got, err := d.Find(ctx, "ephemeral")
+	if err != nil {
+		t.Fatalf("Find: %s", err)
+	}
+	want := *changed
+	want.UpdatedAt = got.UpdatedAt
+	checkLexeme(t, got, &want)
+	if got.UpdatedAt.Before(before) {
+		t.Errorf("Update didn't bump updatedAt, got %s", got.UpdatedAt)
+	}
+}
+
+func testUpdateKeepsCreatedAt(t *testing.T, ctx context.Context, d types.Dictionary) {
+	lexeme := newLexeme("ephemeral")
+	lexeme.CreatedAt, lexeme.UpdatedAt = at("2020-01-02 03:04:05"), at("2020-01-02 03:04:05")
+	if err := d.Save(ctx, lexeme); err != nil {
+		t.Fatalf("Save: %s", err)
+	}
+	changed := newLexeme("ephemeral")
+	changed.Notes = "another note"
+	if err := d.Update(ctx, changed); err != nil {
+		t.Fatalf("Update: %s", err)
+	}
+	got, err := d.Find(ctx, "ephemeral")
+	if err != nil {
+		t.Fatalf("Find: %s", err)
+	}
+	if !sameTime(got.CreatedAt, lexeme.CreatedAt) {
+		t.Errorf("Update without createdAt changed it from %s to %s", lexeme.CreatedAt, got.CreatedAt)
+	}
+	if got.Notes != changed.Notes {
+		t.Errorf("Update didn't change the notes, got %q", got.Notes)
+	}
+}
+
+func testUpdateMissing(t *testing.T, ctx context.Context, d types.Dictionary) {
+	if err := d.Update(ctx, newLexeme("missing")); !errors.Is(err, types.NotFound) {
+		t.Errorf("Update of a missing word returned %v, want types.NotFound", err)
+	}
+	if _, err := d.Find(ctx, "missing"); !errors.Is(err, types.NotFound) {
+		t.Errorf("Update of a missing word saved it")
+	}
+}
+
+func testUpsert(t *testing.T, ctx context.Context, d types.Dictionary) {
+	lexeme := newLexeme("ephemeral")
+	lexeme.CreatedAt, lexeme.UpdatedAt = at("2020-01-02 03:04:05"), at("2020-01-02 03:04:05")
+	if err := d.Upsert(ctx, lexeme); err != nil {
+		t.Fatalf("Upsert of a new word: %s", err)
+	}
+	got, err := d.Find(ctx, "ephemeral")
+	if err != nil {
+		t.Fatalf("Find: %s", err)
+	}
+	checkLexeme(t, got, lexeme)
+
+	changed := newLexeme("ephemeral")
+	changed.Notes = "another note"
+	changed.CreatedAt, changed.UpdatedAt = at("2019-01-01 00:00:00"), at("2022-01-01 00:00:00")
+	if err := d.Upsert(ctx, changed); err != nil {
+		t.Fatalf("Upsert of an existing word: %s", err)
+	}
+	got, err = d.Find(ctx, "ephemeral")
+	if err != nil {
+		t.Fatalf("Find: %s", err)
+	}
+	checkLexeme(t, got, changed)
+}
+
+func testRemove(t *testing.T, ctx context.Context, d types.Dictionary) {
+	for _, name := range []string{"ephemeral", "transient"} {
+		if err := d.Save(ctx, newLexeme(name)); err != nil {
+			t.Fatalf("Save: %s", err)
+		}
+	}
+	if err := d.Remove(ctx, "ephemeral"); err != nil {
+		t.Fatalf("Remove: %s", err)
+	}
+	if _, err := d.Find(ctx, "ephemeral"); !errors.Is(err, types.NotFound) {
+		t.Errorf("Find of a removed word returned %v, want types.NotFound", err)
+	}
+	if _, err := d.Find(ctx, "transient"); err != nil {
+		t.Errorf("Remove removed another word: %s", err)
+	}
+	if err := d.Save(ctx, newLexeme("ephemeral")); err != nil {
+		t.Errorf("Save of a removed word: %s", err)
+	}
+}
+
+func testRemoveMissing(t *testing.T, ctx context.Context, d types.Dictionary) {
+	if err := d.Remove(ctx, "missing"); !errors.Is(err, types.NotFound) {
+		t.Errorf("Remove of a missing word returned %v, want types.NotFound", err)
+	}
+}
+
+func testList(t *testing.T, ctx context.Context, d types.Dictionary) {
+	all, err := d.List(ctx)
+	if err != nil {
+		t.Fatalf("List: %s", err)
+	}
+	if len(all) != 0 {
+		t.Errorf("List of an empty dictionary returned %d words", len(all))
+	}
+
+	want := []string{"apple", "ephemeral", "transient"}
+	saved := make(map[string]*types.Lexeme)
+	for _, name := range []string{"transient", "apple", "ephemeral"} {
+		lexeme := newLexeme(name)
+		if err := d.Save(ctx, lexeme); err != nil {
+			t.Fatalf("Save: %s", err)
+		}
+		saved[name] = lexeme
+	}
+	all, err = d.List(ctx)
+	if err != nil {
+		t.Fatalf("List: %s", err)
+	}
+	var names []string
+	for _, lexeme := range all {
+		names = append(names, lexeme.Name)
+		if s, ok := saved[lexeme.Name]; ok {
+			checkLexeme(t, lexeme, s)
+		}
+	}
+	sort.Strings(names)
+	if strings.Join(names, ",") != strings.Join(want, ",") {
+		t.Errorf("List returned %v, want %v", names, want)
+	}
+}
+
+func testStats(t *testing.T, ctx context.Context, d types.Dictionary) {
+	for i := 0; i < 3; i++ {
+		if err := d.Save(ctx, newLexeme(fmt.Sprintf("word%d", i))); err != nil {
+			t.Fatalf("Save: %s", err)
+		}
+	}
+	stats, err := d.Stats(ctx)
+	if err != nil {
+		t.Fatalf("Stats: %s", err)
+	}
+	if len(stats) == 0 || stats[0].Name != "Words" || stats[0].Value != 3 {
+		t.Errorf("Stats returned %v, want 3 Words first", stats)
+	}
+}
+
+// checkLexeme compares the fields of a lexeme read from the dictionary with the one written.
+func checkLexeme(t *testing.T, got, want *types.Lexeme) {
+	t.Helper()
+	if got.Name != want.Name {
+		t.Errorf("name: got %q, want %q", got.Name, want.Name)
+	}
+	if got.Definition != want.Definition {
+		t.Errorf("%s: definition: got %s, want %s", want.Name, got.Definition, want.Definition)
+	}
+	if got.Source != want.Source {
+		t.Errorf("%s: source: got %q, want %q", want.Name, got.Source, want.Source)
+	}
+	if strings.Join(got.Tags, ",") != strings.Join(want.Tags, ",") {
+		t.Errorf("%s: tags: got %q, want %q", want.Name, got.Tags, want.Tags)
+	}
+	if got.Notes != want.Notes {
+		t.Errorf("%s: notes: got %q, want %q", want.Name, got.Notes, want.Notes)
+	}
+	if !sameTime(got.CreatedAt, want.CreatedAt) {
+		t.Errorf("%s: createdAt: got %v, want %v", want.Name, got.CreatedAt, want.CreatedAt)
+	}
+	if !sameTime(got.UpdatedAt, want.UpdatedAt) {
+		t.Errorf("%s: updatedAt: got %v, want %v", want.Name, got.UpdatedAt, want.UpdatedAt)
+	}
+}
+
+// sameTime reports whether two timestamps are the same second.
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Unix() == b.Unix()
+}
@@ -1,12 +1,16 @@
 package types
 
 import (
+	"context"
 	"errors"
 	"time"
 )
 
 var NotFound = errors.New("Not fond")
 
+// AlreadyExists is returned when saving a lexeme whose name is already in the dictionary.
+var AlreadyExists = errors.New("already exists")
+
 // Quote represents a quote.
 type Quote struct {
 	Text            string `json:"text,omitempty"`            // t
@@ -56,16 +60,36 @@ type Cognate struct {
 	Targets []string `json:"targets"`
 }
 
+// Variant is another spelling of a headword, e.g. "or" (the label) "colour".
+type Variant struct {
+	Label          string          `json:"label,omitempty"`          // vl
+	Text           string          `json:"text,omitempty"`           // va
+	Pronunciations []Pronunciation `json:"pronunciations,omitempty"` // prs
+}
+
+// Paragraph is a usage or synonym discussion: a few paragraphs about the word, with examples.
+type Paragraph struct {
+	Label               string   `json:"label,omitempty"`               // pl
+	Text                string   `json:"text,omitempty"`                // pt
+	VerbalIllustrations []string `json:"verbalIllustrations,omitempty"` // vis in pt
+}
+
 // Entry represents a meaning intended or conveyed.
 type Entry struct {
-	Meta                Meta      `json:"meta,omitempty"`
-	Headword            Headword  `json:"headword,omitempty"`
-	Cognates            []Cognate `json:"cognates"`
-	GrammaticalFunction string    `json:"grammaticalFunction,omitempty"`
-	ShortDefinitions    []string  `json:"shortDefinitions,omitempty"`
-	Defs                []Def     `json:"defs,omitempty"`
-	Quotes              []Quote   `json:"quotes,omitempty"`
-	Etymology           []string  `json:"etymology,omitempty"`
+	Meta                Meta        `json:"meta,omitempty"`
+	Headword            Headword    `json:"headword,omitempty"`
+	AlternateHeadwords  []Headword  `json:"alternateHeadwords,omitempty"` // ahws
+	Variants            []Variant   `json:"variants,omitempty"`           // vrs
+	Cognates            []Cognate   `json:"cognates"`
+	GrammaticalFunction string      `json:"grammaticalFunction,omitempty"`
+	Labels              []string    `json:"labels,omitempty"`        // lbs, e.g. "often capitalized"
+	SubjectLabels       []string    `json:"subjectLabels,omitempty"` // sls, e.g. "British"
+	ShortDefinitions    []string    `json:"shortDefinitions,omitempty"`
+	Defs                []Def       `json:"defs,omitempty"`
+	Usages              []Paragraph `json:"usages,omitempty"`             // usages
+	SynonymDiscussions  []Paragraph `json:"synonymDiscussions,omitempty"` // syns
+	Quotes              []Quote     `json:"quotes,omitempty"`
+	Etymology           []string    `json:"etymology,omitempty"`
 }
 
 // Lexeme represents a linguistic unit.
@@ -85,6 +109,8 @@ type Lexeme struct {
 	Source     string     `db:"source" json:"source"`
 	CreatedAt  *time.Time `db:"createdAt" json:"created_at"`
 	UpdatedAt  *time.Time `db:"updatedAt" json:"updated_at"`
+	Tags       []string   `db:"tags" json:"tags,omitempty"`
+	Notes      string     `db:"notes" json:"notes,omitempty"`
 }
 
 // Stat represents a statistic
@@ -93,11 +119,80 @@ type Stat struct {
 	Value float64 `json:"value"`
 }
 
-// Dictionary defines the operations that every dictionary must implement.
+// locationKey is the context key of the display time zone.
+type locationKey struct{}
+
+// WithLocation returns a copy of ctx that carries the display time zone, e.g. where days start for
+// the statistics of the dictionaries.
+func WithLocation(ctx context.Context, loc *time.Location) context.Context {
+	return context.WithValue(ctx, locationKey{}, loc)
+}
+
+// Location returns the display time zone of ctx, or the local zone of the system if it has none.
+func Location(ctx context.Context) *time.Location {
+	if loc, ok := ctx.Value(locationKey{}).(*time.Location); ok && loc != nil {
+		return loc
+	}
+	return time.Local
+}
+
+// AddedStats returns the statistics of a dictionary whose words were created at the given times:
+// the number of words and how many of them were added today, in the last 7 days and this month.
+// Days start at midnight in loc.
+func AddedStats(created []time.Time, loc *time.Location) []Stat {
+	y, m, d := time.Now().In(loc).Date()
+	today := time.Date(y, m, d, 0, 0, 0, 0, loc)
+	week := today.AddDate(0, 0, -6)
+	month := time.Date(y, m, 1, 0, 0, 0, 0, loc)
+
+	var daily, weekly, monthly int
+	for _, t := range created {
+		if !t.Before(today) {
+			daily++
+		}
+		if !t.Before(week) {
+			weekly++
+		}
+		if !t.Before(month) {
+			monthly++
+		}
+	}
+	return []Stat{
+		{Name: "Words", Value: float64(len(created))},
+		{Name: "Added today", Value: float64(daily)},
+		{Name: "Added in the last 7 days", Value: float64(weekly)},
+		{Name: "Added this month", Value: float64(monthly)},
+	}
+}
+
+// Dictionary defines the operations that every dictionary must implement. Operations that may
+// block on I/O accept a context so they can be cancelled. Timestamps are kept to the second at
+// least. The dictionarytest package checks that an implementation follows these rules.
 type Dictionary interface {
-	Find(name string) (*Lexeme, error)
-	Save(lexeme *Lexeme) error
-	Remove(name string) error
-	Stats() ([]Stat, error)
+	// Find returns the lexeme called name. Returns NotFound if it does not exist.
+	Find(ctx context.Context, name string) (*Lexeme, error)
+	// Save adds a new lexeme, setting the timestamps that are nil to the current time. Returns
+	// AlreadyExists if a lexeme with the same name exists.
+	Save(ctx context.Context, lexeme *Lexeme) error
+	// Update replaces an existing lexeme and bumps its updatedAt timestamp. The createdAt timestamp
+	// is kept if the lexeme doesn't set it. Returns NotFound if the lexeme does not exist.
+	Update(ctx context.Context, lexeme *Lexeme) error
+	// Upsert saves the lexeme if it does not exist or updates it otherwise. Timestamps set by the
+	// caller are preserved.
+	Upsert(ctx context.Context, lexeme *Lexeme) error
+	// Remove deletes a lexeme. Returns NotFound if it does not exist.
+	Remove(ctx context.Context, name string) error
+	// List returns every lexeme in the dictionary.
+	List(ctx context.Context) ([]*Lexeme, error)
+	// Stats returns statistics about the dictionary, starting with the number of words in a stat
+	// named "Words".
+	Stats(ctx context.Context) ([]Stat, error)
 	Close() error
 }
+
+// Modifier is implemented by dictionaries that can change a lexeme atomically: fn is applied to
+// the saved lexeme, which is then updated, without other writers changing it in between. fn may
+// be called again if the change has to be retried.
+type Modifier interface {
+	Modify(ctx context.Context, name string, fn func(lexeme *Lexeme) error) error
+}
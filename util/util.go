@@ -13,6 +13,16 @@ func Min(a, b int) int {
 	return b
 }
 
+// Contains reports whether s is in strs.
+func Contains(strs []string, s string) bool {
+	for _, e := range strs {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
+
 // QuoteStrings wraps in string with double quotes and returns the result.
 func QuoteStrings(strs []string) []string {
 	var res []string
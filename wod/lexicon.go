@@ -0,0 +1,57 @@
+package wod
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"lexicon/types"
+	"math/rand"
+	"sort"
+)
+
+// lexiconSource picks the word of the day among the saved words that haven't been the word of the
+// day yet, so the lexicon can be reviewed one word a day. Once every word has been picked it starts
+// over. The pick only depends on the date and the words, so a date always gets the same word until
+// the lexicon changes, and for good if the words are cached.
+type lexiconSource struct {
+	dictionary types.Dictionary
+	cache      Cache
+}
+
+func (s lexiconSource) Get(ctx context.Context, date string) (*types.Wod, error) {
+	lexemes, err := s.dictionary.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(lexemes) == 0 {
+		return nil, errors.New("the lexicon is empty")
+	}
+
+	reviewed := make(map[string]bool)
+	if s.cache != nil {
+		wods, err := s.cache.ListWods(ctx, Lexicon)
+		if err != nil {
+			return nil, err
+		}
+		for _, w := range wods {
+			reviewed[w.Word] = true
+		}
+	}
+
+	var candidates, all []string
+	for _, lex := range lexemes {
+		all = append(all, lex.Name)
+		if !reviewed[lex.Name] {
+			candidates = append(candidates, lex.Name)
+		}
+	}
+	if len(candidates) == 0 {
+		candidates = all
+	}
+	sort.Strings(candidates)
+
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(date))
+	r := rand.New(rand.NewSource(int64(h.Sum64())))
+	return &types.Wod{Date: date, Word: candidates[r.Intn(len(candidates))]}, nil
+}
@@ -0,0 +1,87 @@
+package wod
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"lexicon/types"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const mwFeedURL = "https://www.merriam-webster.com/wotd/feed/rss2"
+
+// mwSource reads the words of the day from the RSS feed of Merriam-Webster. The feed only holds the
+// last few days, older dates aren't found. It's downloaded once and the dates are looked up in it.
+type mwSource struct {
+	feed string
+
+	mu    sync.Mutex
+	words map[string]string // Words by date, nil until the feed is read.
+}
+
+type rss struct {
+	Items []struct {
+		Title   string `xml:"title"`
+		PubDate string `xml:"pubDate"`
+	} `xml:"channel>item"`
+}
+
+func (s *mwSource) Get(ctx context.Context, date string) (*types.Wod, error) {
+	words, err := s.read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	word, ok := words[date]
+	if !ok {
+		return nil, notFound(date)
+	}
+	return &types.Wod{Date: date, Word: word}, nil
+}
+
+// read downloads the feed on the first call and returns its words by date. Concurrent calls wait
+// for the download, and a failed one is tried again by the next call.
+func (s *mwSource) read(ctx context.Context) (map[string]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.words != nil {
+		return s.words, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.feed, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned %s", s.feed, res.Status)
+	}
+
+	var feed rss
+	if err := xml.NewDecoder(res.Body).Decode(&feed); err != nil {
+		return nil, fmt.Errorf("unable to read the feed: %s", err)
+	}
+	words := make(map[string]string)
+	for _, item := range feed.Items {
+		// The date of the word is the one of the publication in the zone of Merriam-Webster.
+		published, err := time.Parse(time.RFC1123Z, item.PubDate)
+		if err != nil {
+			published, err = time.Parse(time.RFC1123, item.PubDate)
+		}
+		if err != nil {
+			continue
+		}
+		date := published.Format("2006-01-02")
+		if _, ok := words[date]; !ok {
+			words[date] = strings.TrimSpace(item.Title)
+		}
+	}
+	s.words = words
+	return words, nil
+}
@@ -0,0 +1,60 @@
+package wod
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+const feed = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0"><channel>
+<item><title> ephemeral </title><pubDate>Tue, 02 Jan 2024 01:00:00 -0500</pubDate></item>
+<item><title>accept</title><pubDate>Mon, 01 Jan 2024 01:00:00 -0500</pubDate></item>
+<item><title>undated</title><pubDate>yesterday</pubDate></item>
+</channel></rss>`
+
+func TestMerriamWebsterFeedOnce(t *testing.T) {
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			// The first download fails and is tried again.
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(feed))
+	}))
+	defer srv.Close()
+	src := &mwSource{feed: srv.URL}
+	ctx := context.Background()
+
+	if _, err := src.Get(ctx, "2024-01-02"); err == nil {
+		t.Fatal("Get succeeded while the feed was unavailable")
+	}
+
+	want := map[string]string{"2024-01-02": "ephemeral", "2024-01-01": "accept", "2023-12-31": ""}
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		for date, word := range want {
+			wg.Add(1)
+			go func(date, word string) {
+				defer wg.Done()
+				w, err := src.Get(ctx, date)
+				switch {
+				case word == "":
+					if err == nil {
+						t.Errorf("Get(%s) returned %v, want not found", date, w)
+					}
+				case err != nil || w.Word != word || w.Date != date:
+					t.Errorf("Get(%s) returned %v, %v, want %s", date, w, err, word)
+				}
+			}(date, word)
+		}
+	}
+	wg.Wait()
+	if n := atomic.LoadInt32(&requests); n != 2 {
+		t.Errorf("the feed was downloaded %d times, want 2", n)
+	}
+}
@@ -0,0 +1,49 @@
+package wod
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"lexicon/types"
+	"log"
+	"net/http"
+	"net/url"
+)
+
+// serviceSource fetches the words of the day from the wod service at url, one day per request.
+type serviceSource struct {
+	url string
+}
+
+func (s serviceSource) Get(ctx context.Context, date string) (*types.Wod, error) {
+	u := fmt.Sprintf("%s/%s", s.url, url.PathEscape(date))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		log.Printf("HTTP call to %s failed with error: %s", u, err)
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotFound {
+		return nil, notFound(date)
+	}
+
+	buf, err := io.ReadAll(res.Body)
+	if err != nil {
+		log.Printf("Unable to read response's body: %s", err)
+		return nil, err
+	}
+
+	var wod types.Wod
+	if err := json.Unmarshal(buf, &wod); err != nil {
+		log.Printf("Unable to unmarshal response's body: %s", err)
+		return nil, err
+	}
+
+	return &wod, nil
+}
@@ -0,0 +1,107 @@
+// wod fetches the word of the day from one of several sources: the wod service, the feed of
+// Merriam-Webster or the lexicon itself. Fetched words can be cached so that every day is only
+// fetched once.
+package wod
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"lexicon/types"
+	"log"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// Source returns the word of the day of a date in YYYY-MM-DD format.
+type Source interface {
+	Get(ctx context.Context, date string) (*types.Wod, error)
+}
+
+// Cache stores the words of the day already fetched from each source. The SQLite dictionary
+// implements it.
+type Cache interface {
+	// FindWod returns the cached word of the day of source for date, or types.NotFound.
+	FindWod(ctx context.Context, source, date string) (*types.Wod, error)
+	SaveWod(ctx context.Context, source string, wod *types.Wod) error
+	// ListWods returns the cached words of the day of source.
+	ListWods(ctx context.Context, source string) ([]*types.Wod, error)
+}
+
+// Names of the sources other than the wod service, which is selected by its URL.
+const (
+	MerriamWebster = "merriam-webster"
+	Lexicon        = "lexicon"
+)
+
+// IsSource reports whether name selects a source: one of the named sources or the URL of a wod
+// service.
+func IsSource(name string) bool {
+	if name == MerriamWebster || name == Lexicon {
+		return true
+	}
+	u, err := url.Parse(name)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// New returns the source selected by name. The lexicon source picks words from dictionary, it's
+// the only one that needs it. If cache isn't nil, words are looked up in the cache before they're
+// fetched and saved to it after.
+func New(name string, dictionary types.Dictionary, cache Cache) (Source, error) {
+	var src Source
+	switch {
+	case name == MerriamWebster:
+		src = &mwSource{feed: mwFeedURL}
+	case name == Lexicon:
+		if dictionary == nil {
+			return nil, errors.New("the lexicon source needs a dictionary")
+		}
+		src = lexiconSource{dictionary: dictionary, cache: cache}
+	case IsSource(name):
+		src = serviceSource{url: strings.TrimSuffix(name, "/")}
+	default:
+		return nil, fmt.Errorf("unknown word of the day source %q", name)
+	}
+	if cache == nil {
+		return src, nil
+	}
+	return cachedSource{name: name, src: src, cache: cache, mu: &sync.Mutex{}}, nil
+}
+
+// cachedSource looks up words in the cache before fetching them from src. Words may be fetched in
+// parallel, but the cache is used by one goroutine at a time.
+type cachedSource struct {
+	name  string
+	src   Source
+	cache Cache
+	mu    *sync.Mutex
+}
+
+func (c cachedSource) Get(ctx context.Context, date string) (*types.Wod, error) {
+	c.mu.Lock()
+	wod, err := c.cache.FindWod(ctx, c.name, date)
+	c.mu.Unlock()
+	if err == nil {
+		return wod, nil
+	}
+	if !errors.Is(err, types.NotFound) {
+		log.Printf("Unable to read the cached word of the day: %s", err)
+	}
+
+	wod, err = c.src.Get(ctx, date)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.cache.SaveWod(ctx, c.name, wod); err != nil {
+		log.Printf("Unable to cache the word of the day: %s", err)
+	}
+	return wod, nil
+}
+
+// notFound is returned when a source has no word for date.
+func notFound(date string) error {
+	return fmt.Errorf("word of the day for '%s' not found", date)
+}
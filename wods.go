@@ -0,0 +1,230 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"lexicon/types"
+	"lexicon/wod"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mattn/go-isatty"
+)
+
+// maxWodDays is the longest range of days fetched without confirmation.
+const maxWodDays = 31
+
+// wodConcurrency is the number of days fetched in parallel.
+const wodConcurrency = 4
+
+// wodOptions are the flags of the wod command.
+type wodOptions struct {
+	define bool   // Define and save the words instead of printing them.
+	last   string // Relative range ending today, e.g. "7d" or "2w".
+	month  string // Every day of a month, YYYY-MM.
+	yes    bool   // Don't ask before fetching more than maxWodDays.
+}
+
+// wodResult is the word of the day of a date, or the error that prevented getting it.
+type wodResult struct {
+	date string
+	wod  *types.Wod
+	err  error
+}
+
+// wodSource returns the word of the day source of the profile. The words are cached if the
+// dictionary supports it.
+func wodSource(app *app) (wod.Source, error) {
+	cache, _ := app.dictionary.(wod.Cache)
+	return wod.New(app.profile.WodSource, app.dictionary, cache)
+}
+
+// wordOfTheDay prints the words of the day between the dates in args, or in the range selected by
+// the options, today's by default. Days that fail are reported after the others.
+func wordOfTheDay(ctx context.Context, app *app, args []string, opts wodOptions) error {
+	days, err := wodDays(args, opts, app.location)
+	if err != nil {
+		return err
+	}
+	if len(days) > maxWodDays && !opts.yes {
+		ok, err := confirm(ctx, app, fmt.Sprintf("Fetch the words of %d days?", len(days)))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("%d days is more than %d, run with --yes to fetch them anyway", len(days), maxWodDays)
+		}
+	}
+
+	// Without the dictionary the words aren't cached, which is fine unless it's really needed.
+	if app.dictionary == nil {
+		if err := app.open(); err == nil {
+			defer app.close()
+		} else if opts.define || app.profile.WodSource == wod.Lexicon {
+			return err
+		}
+	}
+	source, err := wodSource(app)
+	if err != nil {
+		return err
+	}
+
+	workers := wodConcurrency
+	if app.profile.WodSource == wod.Lexicon {
+		// Each pick depends on the words picked for the previous days.
+		workers = 1
+	}
+	results := fetchWods(ctx, source, days, workers)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	var wods []*types.Wod
+	var failed []wodResult
+	for _, res := range results {
+		if res.err != nil {
+			failed = append(failed, res)
+		} else {
+			wods = append(wods, res.wod)
+		}
+	}
+
+	if opts.define {
+		for _, w := range wods {
+			if err := defineName(ctx, app, strings.ToLower(w.Word), app.mode()); err != nil {
+				failed = append(failed, wodResult{date: w.Date, err: err})
+			}
+		}
+	} else if err := newFormatter(ctx, app).wods(wods); err != nil {
+		return err
+	}
+
+	for _, res := range failed {
+		log.Printf("%s: %s", res.date, res.err)
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%d of %d days failed", len(failed), len(days))
+	}
+	return nil
+}
+
+// wodDays returns the days selected by the dates in args or the range options, in order. Days
+// start at midnight in loc.
+func wodDays(args []string, opts wodOptions, loc *time.Location) ([]time.Time, error) {
+	ranges := 0
+	for _, set := range []bool{len(args) > 0, opts.last != "", opts.month != ""} {
+		if set {
+			ranges++
+		}
+	}
+	if ranges > 1 {
+		return nil, usageError{"dates, --last and --month are mutually exclusive"}
+	}
+
+	// Default date range (today's date in the display time zone)
+	startDate := today(loc)
+	endDate := startDate
+	switch {
+	case opts.last != "":
+		n, err := parseDays(opts.last)
+		if err != nil {
+			return nil, err
+		}
+		startDate = endDate.AddDate(0, 0, 1-n)
+	case opts.month != "":
+		month, err := time.ParseInLocation("2006-01", opts.month, loc)
+		if err != nil {
+			return nil, usageError{fmt.Sprintf("invalid month %q, expected YYYY-MM", opts.month)}
+		}
+		startDate = month
+		// Days after today don't have a word yet.
+		if last := month.AddDate(0, 1, -1); last.Before(endDate) {
+			endDate = last
+		}
+	case len(args) > 0:
+		var err error
+		if startDate, err = parseDate(args[0], loc); err != nil {
+			return nil, err
+		}
+		endDate = startDate
+		if len(args) > 1 {
+			if endDate, err = parseDate(args[1], loc); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if endDate.Before(startDate) {
+		return nil, usageError{fmt.Sprintf("the range ends on %s, before it starts on %s",
+			endDate.Format(dateFormat), startDate.Format(dateFormat))}
+	}
+
+	// Days are added to the date rather than 24 hours so that DST changes don't skip or repeat days.
+	var days []time.Time
+	for d := startDate; !d.After(endDate); d = d.AddDate(0, 0, 1) {
+		days = append(days, d)
+	}
+	return days, nil
+}
+
+// parseDays parses a number of days like "7d" or a number of weeks like "2w".
+func parseDays(s string) (int, error) {
+	unit := 1
+	n := s
+	switch {
+	case strings.HasSuffix(s, "d"):
+		n = strings.TrimSuffix(s, "d")
+	case strings.HasSuffix(s, "w"):
+		n, unit = strings.TrimSuffix(s, "w"), 7
+	}
+	days, err := strconv.Atoi(n)
+	if err != nil || days < 1 {
+		return 0, usageError{fmt.Sprintf("invalid range %q, expected a number of days like 7d or weeks like 2w", s)}
+	}
+	return days * unit, nil
+}
+
+// fetchWods gets the words of the day of days in parallel. The results are in the order of days.
+func fetchWods(ctx context.Context, source wod.Source, days []time.Time, workers int) []wodResult {
+	results := make([]wodResult, len(days))
+	queue := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range queue {
+				date := days[i].Format(dateFormat)
+				w, err := source.Get(ctx, date)
+				results[i] = wodResult{date: date, wod: w, err: err}
+			}
+		}()
+	}
+feed:
+	for i := range days {
+		select {
+		case <-ctx.Done():
+			break feed
+		case queue <- i:
+		}
+	}
+	close(queue)
+	wg.Wait()
+	return results
+}
+
+// confirm asks a yes or no question. The answer is no if the input isn't a terminal.
+func confirm(ctx context.Context, app *app, question string) (bool, error) {
+	if !isatty.IsTerminal(os.Stdin.Fd()) {
+		return false, nil
+	}
+	answer, err := app.readLine(ctx, question+" [y/N] ")
+	if err != nil {
+		return false, err
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes", nil
+}